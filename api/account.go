@@ -3,6 +3,7 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,14 +24,29 @@ func (server *Server) createAccount(c *gin.Context) {
 	}
 
 	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
-	arg := db.CreateAccountParams{
-		Owner:    authPayload.Username,
-		Currency: req.Currency,
-		Balance:  0,
+	idempotency, err := idempotencyParams(c, authPayload.Username, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if idempotency != nil && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+		return
 	}
 
-	account, err := server.store.CreateAccount(c.Request.Context(), arg)
+	arg := db.CreateAccountTxParams{
+		CreateAccountParams: db.CreateAccountParams{
+			Owner:    authPayload.Username,
+			Currency: req.Currency,
+			Balance:  0,
+		},
+		Idempotency: idempotency,
+	}
+
+	account, err := server.store.CreateAccountTx(c.Request.Context(), arg)
 	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+			return
+		}
 		var e *pq.Error
 		if errors.As(err, &e) {
 			switch e.Code.Name() {
@@ -60,47 +76,75 @@ func (server *Server) getAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := server.store.GetAccount(c.Request.Context(), req.ID)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, errorResponse(err))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, errorResponse(err))
+	account, valid := server.readableAccount(c, req.ID)
+	if !valid {
 		return
 	}
 
+	c.JSON(http.StatusOK, account)
+}
+
+// readableAccount loads an account the authenticated user may read, which is any account for admins and auditors
+func (server *Server) readableAccount(c *gin.Context, accountID int64) (db.Account, bool) {
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !canReadAnyAccount(authPayload.Role) {
+		return server.ownedAccount(c, accountID)
+	}
+
+	return server.loadAccount(c, accountID)
+}
+
+// ownedAccount loads an account and makes sure it belongs to the authenticated user
+func (server *Server) ownedAccount(c *gin.Context, accountID int64) (db.Account, bool) {
+	account, valid := server.loadAccount(c, accountID)
+	if !valid {
+		return account, false
+	}
+
 	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
 	if account.Owner != authPayload.Username {
-		err = errors.New("account does not belong to authenticated user")
+		err := errors.New("account does not belong to authenticated user")
 		c.JSON(http.StatusUnauthorized, errorResponse(err))
-		return
+		return account, false
 	}
 
-	c.JSON(http.StatusOK, account)
+	return account, true
 }
 
 type getAccountsRequest struct {
-	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=10"`
-	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+	// Owner lists the accounts of another user, only admins and auditors may set it
+	Owner    string `form:"owner" binding:"omitempty,alphanum"`
+	PageSize int32  `form:"page_size,default=10" binding:"min=1,max=10"`
+	PageID   int32  `form:"page_id,default=1" binding:"min=1"`
 }
 
 func (server *Server) getAccounts(c *gin.Context) {
 	var req getAccountsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 	limit := req.PageSize
 	offset := (req.PageID - 1) * req.PageSize
 
 	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	owner := authPayload.Username
+	if req.Owner != "" && req.Owner != owner {
+		if !canReadAnyAccount(authPayload.Role) {
+			err := errors.New("only admins and auditors can list the accounts of other users")
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		owner = req.Owner
+	}
+
 	arg := db.ListAccountsParams{
-		Owner:  authPayload.Username,
+		Owner:  owner,
 		Limit:  limit,
 		Offset: offset,
 	}
 
-	accounts, err := server.store.ListAccounts(c, arg)
+	accounts, err := server.store.ListAccounts(c.Request.Context(), arg)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -108,3 +152,59 @@ func (server *Server) getAccounts(c *gin.Context) {
 
 	c.JSON(http.StatusOK, accounts)
 }
+
+func (server *Server) freezeAccount(c *gin.Context) {
+	server.setAccountFrozen(c, true)
+}
+
+func (server *Server) unfreezeAccount(c *gin.Context) {
+	server.setAccountFrozen(c, false)
+}
+
+// setAccountFrozen freezes or unfreezes the account of the request uri, a frozen account can neither send nor receive money
+func (server *Server) setAccountFrozen(c *gin.Context, frozen bool) {
+	var req getAccountRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.SetAccountFrozen(c.Request.Context(), db.SetAccountFrozenParams{
+		IsFrozen: frozen,
+		ID:       req.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// loadAccount loads an account, responding with 404 if it does not exist
+func (server *Server) loadAccount(c *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.store.GetAccount(c.Request.Context(), accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+	return account, true
+}
+
+// matchingCurrency makes sure the account holds the given currency
+func (server *Server) matchingCurrency(c *gin.Context, account db.Account, currency string) bool {
+	if account.Currency != currency {
+		err := fmt.Errorf("invalid currency %s, account currency %s", currency, account.Currency)
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+	return true
+}
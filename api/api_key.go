@@ -0,0 +1,150 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	rsp := apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if apiKey.LastUsedAt.Valid {
+		rsp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	return rsp
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,unique,dive,scope"`
+}
+
+type createAPIKeyResponse struct {
+	// Key is only ever returned here, only its hash is stored
+	Key string `json:"key"`
+	apiKeyResponse
+}
+
+// createAPIKey creates an API key for the authenticated user, it can do what the scopes allow within the role of the user
+func (server *Server) createAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	key, prefix, err := util.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	apiKey, err := server.store.CreateAPIKey(c.Request.Context(), db.CreateAPIKeyParams{
+		Username: authPayload.Username,
+		Name:     req.Name,
+		Prefix:   prefix,
+		KeyHash:  util.HashSecureToken(key),
+		Scopes:   req.Scopes,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := createAPIKeyResponse{
+		Key:            key,
+		apiKeyResponse: newAPIKeyResponse(apiKey),
+	}
+	c.JSON(http.StatusCreated, rsp)
+}
+
+type listAPIKeysRequest struct {
+	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=50"`
+	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+}
+
+// listAPIKeys lists the API keys of the authenticated user that have not been revoked, newest first
+func (server *Server) listAPIKeys(c *gin.Context) {
+	var req listAPIKeysRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	apiKeys, err := server.store.ListAPIKeys(c.Request.Context(), db.ListAPIKeysParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		rsp[i] = newAPIKeyResponse(apiKey)
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+type revokeAPIKeyRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeAPIKey revokes an API key of the authenticated user, requests with it are rejected right away
+func (server *Server) revokeAPIKey(c *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	apiKey, err := server.store.GetAPIKey(c.Request.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if apiKey.Username != authPayload.Username {
+		err := errors.New("api key does not belong to authenticated user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	err = server.store.RevokeAPIKey(c.Request.Context(), apiKey.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
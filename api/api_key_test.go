@@ -0,0 +1,354 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomAPIKey(t *testing.T, username string, scopes ...string) (db.ApiKey, string) {
+	key, prefix, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	apiKey := db.ApiKey{
+		ID:        util.RandomInt(1, 1000),
+		Username:  username,
+		Name:      util.RandomString(10),
+		Prefix:    prefix,
+		KeyHash:   util.HashSecureToken(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	return apiKey, key
+}
+
+// addAPIKeyAuthorization authenticates the request with the API key and stubs its lookup
+func addAPIKeyAuthorization(request *http.Request, store *mockdb.MockStore, apiKey db.ApiKey, key string, role string) {
+	arg := db.UseAPIKeyParams{
+		Prefix:  apiKey.Prefix,
+		KeyHash: apiKey.KeyHash,
+	}
+	store.EXPECT().UseAPIKey(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.UseAPIKeyRow{
+		ID:        apiKey.ID,
+		Username:  apiKey.Username,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		KeyHash:   apiKey.KeyHash,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+		Role:      role,
+	}, nil)
+
+	request.Header.Set(authorizationKey, fmt.Sprintf("ApiKey %s", key))
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	scopes := []string{util.ScopeAccountsRead, util.ScopeTransfersWrite}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":   "back office",
+				"scopes": scopes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "back office", arg.Name)
+						require.Equal(t, scopes, arg.Scopes)
+
+						return db.ApiKey{
+							ID:        1,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							KeyHash:   arg.KeyHash,
+							Scopes:    arg.Scopes,
+							CreatedAt: time.Now(),
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp createAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), rsp.ID)
+				require.Equal(t, scopes, rsp.Scopes)
+				require.Nil(t, rsp.LastUsedAt)
+				require.NotContains(t, recorder.Body.String(), "key_hash")
+
+				prefix, ok := util.ParseAPIKeyPrefix(rsp.Key)
+				require.True(t, ok)
+				require.Equal(t, prefix, rsp.Prefix)
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			body: gin.H{
+				"name":   "back office",
+				"scopes": []string{"users:write"},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{
+				"name":   "back office",
+				"scopes": []string{},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "WithAPIKey",
+			body: gin.H{
+				"name":   "back office",
+				"scopes": scopes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				apiKey, key := randomAPIKey(t, user.Username, util.ScopeAccountsRead, util.ScopeAccountsWrite, util.ScopeTransfersRead, util.ScopeTransfersWrite)
+				addAPIKeyAuthorization(request, store, apiKey, key, util.DepositorRole)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"name":   "back office",
+				"scopes": scopes,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/api_keys", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker, store)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, _ := randomAPIKey(t, user.Username, util.ScopeAccountsRead)
+	apiKey.LastUsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListAPIKeysParams{
+					Username: user.Username,
+					Limit:    5,
+					Offset:   0,
+				}
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Eq(arg)).Times(1).Return([]db.ApiKey{apiKey}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), apiKey.KeyHash)
+
+				var rsp []apiKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp, 1)
+				require.Equal(t, apiKey.ID, rsp[0].ID)
+				require.Equal(t, apiKey.Prefix, rsp[0].Prefix)
+				require.NotNil(t, rsp[0].LastUsedAt)
+				require.WithinDuration(t, apiKey.LastUsedAt.Time, *rsp[0].LastUsedAt, time.Second)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/api_keys"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey, _ := randomAPIKey(t, user.Username, util.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		apiKeyID      int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			apiKeyID: apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(apiKey, nil)
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			apiKeyID: apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "APIKeyOfOtherUser",
+			apiKeyID: apiKey.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				other := apiKey
+				other.Username = util.RandomOwner()
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(apiKey.ID)).Times(1).Return(other, nil)
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidID",
+			apiKeyID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/api_keys/%d", tc.apiKeyID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+)
+
+const (
+	batchModeAtomic     = "atomic"
+	batchModeBestEffort = "best_effort"
+)
+
+type batchTransferItemRequest struct {
+	ToAccountID int64 `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64 `json:"amount" binding:"required,gt=0"`
+}
+
+type batchTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	// Mode atomic runs every item or none of them, best_effort skips and reports the items failing for insufficient funds
+	Mode  string                     `json:"mode" binding:"required,oneof=atomic best_effort"`
+	Items []batchTransferItemRequest `json:"items" binding:"required,min=1,max=500,dive"`
+}
+
+func (server *Server) createBatchTransfer(c *gin.Context) {
+	var req batchTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	idempotency, err := idempotencyParams(c, authPayload.Username, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if idempotency != nil && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+		return
+	}
+
+	fromAccount, valid := server.ownedAccount(c, req.FromAccountID)
+	if !valid || !server.matchingCurrency(c, fromAccount, req.Currency) {
+		return
+	}
+	if !server.validBatchDestinations(c, req) {
+		return
+	}
+
+	arg := db.BatchTransferTxParams{
+		FromAccountID: fromAccount.ID,
+		Items:         make([]db.BatchTransferItem, len(req.Items)),
+		Atomic:        req.Mode == batchModeAtomic,
+		Idempotency:   idempotency,
+	}
+	var total int64
+	for i, item := range req.Items {
+		arg.Items[i] = db.BatchTransferItem{
+			ToAccountID: item.ToAccountID,
+			Amount:      item.Amount,
+		}
+		total = addAmounts(total, item.Amount)
+	}
+	// a batch cannot be held as a pending transfer, so its total is confirmed with a step-up token instead
+	if !server.stepUpConfirmed(c, req.Currency, total) {
+		return
+	}
+
+	result, err := server.store.BatchTransferTx(c.Request.Context(), arg)
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// validBatchDestinations loads every destination account of a batch at once and makes sure
+// they all exist, hold the batch currency and are not settlement accounts
+func (server *Server) validBatchDestinations(c *gin.Context, req batchTransferRequest) bool {
+	ids := make([]int64, len(req.Items))
+	for i, item := range req.Items {
+		ids[i] = item.ToAccountID
+	}
+
+	accounts, err := server.store.ListAccountsByIDs(c.Request.Context(), ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	byID := make(map[int64]db.Account, len(accounts))
+	for _, account := range accounts {
+		byID[account.ID] = account
+	}
+
+	for i, item := range req.Items {
+		account, ok := byID[item.ToAccountID]
+		switch {
+		case !ok:
+			err = fmt.Errorf("item %d: account %d not found", i, item.ToAccountID)
+		case account.Currency != req.Currency:
+			err = fmt.Errorf("item %d: invalid currency %s, account currency %s", i, req.Currency, account.Currency)
+		case account.Owner == db.SystemUsername:
+			err = fmt.Errorf("item %d: cannot transfer to a settlement account", i)
+		default:
+			continue
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,349 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	fromAccount := randomAccount(user.Username)
+	toAccount1 := randomAccount(util.RandomOwner())
+	toAccount1.ID = fromAccount.ID + 1
+	toAccount1.Currency = fromAccount.Currency
+	toAccount2 := randomAccount(util.RandomOwner())
+	toAccount2.ID = fromAccount.ID + 2
+	toAccount2.Currency = fromAccount.Currency
+
+	items := []batchTransferItemRequest{
+		{ToAccountID: toAccount1.ID, Amount: util.RandomInt(1, 100)},
+		{ToAccountID: toAccount2.ID, Amount: util.RandomInt(1, 100)},
+	}
+	ids := []int64{toAccount1.ID, toAccount2.ID}
+
+	// each item is below the step-up threshold but their total is above it
+	highValueItems := []batchTransferItemRequest{
+		{ToAccountID: toAccount1.ID, Amount: 600},
+		{ToAccountID: toAccount2.ID, Amount: 600},
+	}
+
+	transfer := createTransfer(fromAccount.ID, toAccount1.ID, items[0].Amount)
+	result := db.BatchTransferTxResult{
+		FromAccount: fromAccount,
+		Items: []db.BatchTransferItemResult{
+			{ToAccountID: toAccount1.ID, Amount: items[0].Amount, Status: db.BatchItemSucceeded, Transfer: &transfer},
+			{ToAccountID: toAccount2.ID, Amount: items[1].Amount, Status: db.BatchItemFailed, Error: db.ErrInsufficientFunds.Error()},
+		},
+		Succeeded: 1,
+		Failed:    1,
+	}
+
+	testCases := []struct {
+		name          string
+		reqBody       batchTransferRequest
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKBestEffort",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeBestEffort,
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.BatchTransferTxParams{
+					FromAccountID: fromAccount.ID,
+					Items: []db.BatchTransferItem{
+						{ToAccountID: toAccount1.ID, Amount: items[0].Amount},
+						{ToAccountID: toAccount2.ID, Amount: items[1].Amount},
+					},
+				}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, toAccount2}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				data, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+
+				var gotResult db.BatchTransferTxResult
+				err = json.Unmarshal(data, &gotResult)
+				require.NoError(t, err)
+				require.Equal(t, result.Succeeded, gotResult.Succeeded)
+				require.Equal(t, result.Failed, gotResult.Failed)
+				require.Len(t, gotResult.Items, 2)
+				require.Equal(t, transfer.ID, gotResult.Items[0].Transfer.ID)
+				require.Nil(t, gotResult.Items[1].Transfer)
+			},
+		},
+		{
+			name: "AtomicInsufficientFunds",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, toAccount2}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.BatchTransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "Unauthorized",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "DestinationNotFound",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DestinationCurrencyMismatch",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				otherAccount := toAccount2
+				otherAccount.Currency = otherCurrency(fromAccount.Currency)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, otherAccount}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidMode",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          "sometimes",
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidItemAmount",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         []batchTransferItemRequest{{ToAccountID: toAccount1.ID, Amount: -1}},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoItems",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalServerError",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         items,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, toAccount2}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.BatchTransferTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "StepUpRequired",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         highValueItems,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, toAccount2}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "StepUpTokenOfOtherSession",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         highValueItems,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+
+				stepUpToken, _, err := tokenMaker.CreateSessionToken(user.Username, util.DepositorRole, uuid.New(), token.TokenTypeStepUp, time.Minute)
+				require.NoError(t, err)
+				request.Header.Set(stepUpTokenHeader, stepUpToken)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, toAccount2}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "OKWithStepUpToken",
+			reqBody: batchTransferRequest{
+				FromAccountID: fromAccount.ID,
+				Currency:      fromAccount.Currency,
+				Mode:          batchModeAtomic,
+				Items:         highValueItems,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addStepUpAuthorization(t, request, tokenMaker, user.Username, util.DepositorRole)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().ListAccountsByIDs(gomock.Any(), gomock.Eq(ids)).Times(1).Return([]db.Account{toAccount1, toAccount2}, nil)
+				store.EXPECT().BatchTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.BatchTransferTxResult{Succeeded: 2}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.reqBody)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+)
+
+type listAccountEntriesRequest struct {
+	PageSize  int32      `form:"page_size,default=10" binding:"min=1,max=10"`
+	PageID    int32      `form:"page_id,default=1" binding:"min=1"`
+	StartTime *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   *time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func (server *Server) listAccountEntries(c *gin.Context) {
+	var uri getAccountRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAccountEntriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.StartTime != nil && req.EndTime != nil && !req.StartTime.Before(*req.EndTime) {
+		err := errors.New("start_time must be before end_time")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.readableAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	arg := db.AccountStatementParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+	if req.StartTime != nil {
+		arg.StartTime = sql.NullTime{Time: *req.StartTime, Valid: true}
+	}
+	if req.EndTime != nil {
+		arg.EndTime = sql.NullTime{Time: *req.EndTime, Valid: true}
+	}
+
+	statement, err := server.store.AccountStatement(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/fx"
+	"github.com/hanifsyahsn/simple_bank/token"
+)
+
+type fxQuoteRequest struct {
+	FromCurrency string `form:"from_currency" binding:"required,currency"`
+	ToCurrency   string `form:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+	Amount       int64  `form:"amount" binding:"omitempty,gt=0"`
+}
+
+type fxQuoteResponse struct {
+	ID           uuid.UUID `json:"id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         string    `json:"rate"`
+	Amount       int64     `json:"amount,omitempty"`
+	ToAmount     int64     `json:"to_amount,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+func (server *Server) createFxQuote(c *gin.Context) {
+	var req fxQuoteRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	rate, valid := server.exchangeRate(c, req.FromCurrency, req.ToCurrency)
+	if !valid {
+		return
+	}
+
+	var toAmount int64
+	if req.Amount > 0 {
+		var err error
+		toAmount, err = fx.Convert(req.Amount, rate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	quote, err := server.store.CreateFxQuote(c.Request.Context(), db.CreateFxQuoteParams{
+		ID:           uuid.New(),
+		Username:     authPayload.Username,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Rate:         fx.FormatRate(rate),
+		ExpiresAt:    time.Now().Add(server.config.FxQuoteDuration),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := fxQuoteResponse{
+		ID:           quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		Rate:         quote.Rate,
+		Amount:       req.Amount,
+		ToAmount:     toAmount,
+		ExpiresAt:    quote.ExpiresAt,
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+// exchangeRate looks up the current rate between two currencies
+func (server *Server) exchangeRate(c *gin.Context, from, to string) (*big.Rat, bool) {
+	rate, err := server.rates.Rate(c.Request.Context(), from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrRateNotFound) {
+			err = fmt.Errorf("%w from %s to %s", err, from, to)
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	return rate, true
+}
+
+// validQuote loads a quote and makes sure the authenticated user can still use it for the given currencies
+func (server *Server) validQuote(c *gin.Context, quoteID uuid.UUID, from, to string) (db.FxQuote, *big.Rat, bool) {
+	quote, err := server.store.GetFxQuote(c.Request.Context(), quoteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return quote, nil, false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return quote, nil, false
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if quote.Username != authPayload.Username {
+		err = errors.New("quote does not belong to authenticated user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return quote, nil, false
+	}
+	if quote.FromCurrency != from || quote.ToCurrency != to {
+		err = fmt.Errorf("quote is for %s to %s, transfer is %s to %s", quote.FromCurrency, quote.ToCurrency, from, to)
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return quote, nil, false
+	}
+	if time.Now().After(quote.ExpiresAt) {
+		err = errors.New("quote has expired")
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		return quote, nil, false
+	}
+
+	rate, err := fx.ParseRate(quote.Rate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return quote, nil, false
+	}
+	return quote, rate, true
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+)
+
+type createHoldRequest struct {
+	ToAccountID int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount      int64  `json:"amount" binding:"required,gt=0"`
+	Currency    string `json:"currency" binding:"required,currency"`
+	// ExpiresIn is the lifetime of the hold in seconds, it defaults to and cannot exceed the configured hold duration
+	ExpiresIn int64 `json:"expires_in" binding:"omitempty,gt=0"`
+}
+
+func (server *Server) createHold(c *gin.Context) {
+	var uri getAccountRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req createHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	duration := server.config.HoldDuration
+	if req.ExpiresIn > 0 {
+		duration = time.Duration(req.ExpiresIn) * time.Second
+		if duration > server.config.HoldDuration {
+			err := fmt.Errorf("expires_in must not exceed %d seconds", int64(server.config.HoldDuration/time.Second))
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	account, valid := server.ownedAccount(c, uri.ID)
+	if !valid || !server.matchingCurrency(c, account, req.Currency) {
+		return
+	}
+
+	toAccount, valid := server.validAccount(c, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+	if toAccount.Owner == db.SystemUsername {
+		err := errors.New("cannot hold funds for a settlement account, use a withdrawal instead")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, err := server.store.AuthorizeHold(c.Request.Context(), db.AuthorizeHoldParams{
+		AccountID:   account.ID,
+		ToAccountID: toAccount.ID,
+		Amount:      req.Amount,
+		ExpiresAt:   time.Now().Add(duration),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, hold)
+}
+
+type listHoldsRequest struct {
+	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=10"`
+	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+}
+
+func (server *Server) listHolds(c *gin.Context) {
+	var uri getAccountRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listHoldsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.readableAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	holds, err := server.store.ListHolds(c.Request.Context(), db.ListHoldsParams{
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+type holdRequest struct {
+	ID     int64 `uri:"id" binding:"required,min=1"`
+	HoldID int64 `uri:"hold_id" binding:"required,min=1"`
+}
+
+func (server *Server) getHold(c *gin.Context) {
+	var uri holdRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.readableAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	hold, valid := server.accountHold(c, account, uri.HoldID)
+	if !valid {
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+type captureHoldRequest struct {
+	// Amount is the part of the hold to capture, the whole hold is captured when it is omitted
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+func (server *Server) captureHold(c *gin.Context) {
+	var uri holdRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req captureHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.ownedAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	hold, valid := server.accountHold(c, account, uri.HoldID)
+	if !valid {
+		return
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = hold.Amount
+	}
+	if !server.stepUpConfirmed(c, account.Currency, amount) {
+		return
+	}
+
+	result, err := server.store.CaptureHold(c.Request.Context(), db.CaptureHoldParams{
+		HoldID: hold.ID,
+		Amount: req.Amount,
+	})
+	if err != nil {
+		server.holdError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (server *Server) voidHold(c *gin.Context) {
+	var uri holdRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.ownedAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	hold, valid := server.accountHold(c, account, uri.HoldID)
+	if !valid {
+		return
+	}
+
+	hold, err := server.store.VoidHold(c.Request.Context(), hold.ID)
+	if err != nil {
+		server.holdError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hold)
+}
+
+// accountHold loads a hold and makes sure it was placed on the given account
+func (server *Server) accountHold(c *gin.Context, account db.Account, holdID int64) (db.Hold, bool) {
+	hold, err := server.store.GetHold(c.Request.Context(), holdID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return hold, false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return hold, false
+	}
+	if hold.AccountID != account.ID {
+		err := fmt.Errorf("hold %d not found on account %d", hold.ID, account.ID)
+		c.JSON(http.StatusNotFound, errorResponse(err))
+		return hold, false
+	}
+
+	return hold, true
+}
+
+// holdError maps the errors of capturing or voiding a hold to a response
+func (server *Server) holdError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrHoldNotActive):
+		c.JSON(http.StatusConflict, errorResponse(err))
+	case errors.Is(err, db.ErrCaptureExceedsHold), errors.Is(err, db.ErrInsufficientFunds):
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+	case errors.Is(err, db.ErrAccountFrozen):
+		c.JSON(http.StatusForbidden, errorResponse(err))
+	default:
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	idempotencyContentType   = "application/json; charset=utf-8"
+)
+
+var errIdempotencyKeyMismatch = errors.New("idempotency key has already been used with a different request")
+
+// hashIdempotentRequest fingerprints a request so a reused key can be told apart from a genuine retry,
+// the path is the requested one so the same key and body sent to another account is not a retry
+func hashIdempotentRequest(method, path string, req any) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// idempotencyParams returns nil when the request does not carry an Idempotency-Key header
+func idempotencyParams(c *gin.Context, username string, req any) (*db.IdempotencyParams, error) {
+	key := c.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+
+	requestHash, err := hashIdempotentRequest(c.Request.Method, c.Request.URL.Path, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &db.IdempotencyParams{
+		Username:    username,
+		Key:         key,
+		RequestHash: requestHash,
+	}, nil
+}
+
+// replayIdempotentRequest writes the stored response of an already processed request,
+// and reports whether the request has been handled
+func (server *Server) replayIdempotentRequest(c *gin.Context, arg *db.IdempotencyParams, status int) bool {
+	stored, err := server.store.GetIdempotencyKey(c.Request.Context(), db.GetIdempotencyKeyParams{
+		Username: arg.Username,
+		Key:      arg.Key,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if stored.RequestHash != arg.RequestHash {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(errIdempotencyKeyMismatch))
+		return true
+	}
+
+	c.Header(idempotentReplayedHeader, "true")
+	c.Data(status, idempotencyContentType, stored.ResponseBody)
+	return true
+}
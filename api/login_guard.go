@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+// errInvalidCredentials is returned for unknown users and wrong passwords alike so the login cannot be used
+// to find out which usernames exist
+var errInvalidCredentials = errors.New("invalid username or password")
+
+var errTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// checkDummyPassword spends as long as checking a real password, so unknown users answer as slowly as known ones
+func (server *Server) checkDummyPassword(password string) {
+	server.dummyPasswordHashOnce.Do(func() {
+		server.dummyPasswordHash, _ = server.passwordHasher.HashPassword(util.RandomString(16))
+	})
+	_ = server.passwordHasher.CheckPasswordHash(password, server.dummyPasswordHash)
+}
+
+// loginRetryAfter returns how long the client has to wait before it may try to log in to the username again,
+// zero when it may try right away
+func (server *Server) loginRetryAfter(ctx context.Context, username string, clientIP string) (time.Duration, error) {
+	now := time.Now()
+	since := now.Add(-server.config.LoginLockoutDuration)
+
+	byUsername, err := server.store.GetLoginFailuresByUsername(ctx, db.GetLoginFailuresByUsernameParams{
+		Username: username,
+		Since:    since,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	byClientIP, err := server.store.GetLoginFailuresByClientIP(ctx, db.GetLoginFailuresByClientIPParams{
+		ClientIp: clientIP,
+		Since:    since,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	retryAt := loginBackoff(byUsername.FailedAttempts, byUsername.LastFailedAt, server.config.LoginMaxFailedAttempts,
+		server.config.LoginFailureDelay, server.config.LoginLockoutDuration)
+
+	// a client ip is only locked out, never slowed down, since many users can share one behind a NAT
+	ipRetryAt := loginBackoff(byClientIP.FailedAttempts, byClientIP.LastFailedAt, server.config.LoginMaxFailedAttemptsIP,
+		0, server.config.LoginLockoutDuration)
+	if ipRetryAt.After(retryAt) {
+		retryAt = ipRetryAt
+	}
+
+	if !retryAt.After(now) {
+		return 0, nil
+	}
+	return retryAt.Sub(now), nil
+}
+
+// loginBackoff returns when the next login may be tried after the failures, the delay doubles with every failure
+// and the login is locked for the whole lockout duration once maxFailures is reached
+func loginBackoff(failures int64, lastFailedAt time.Time, maxFailures int64, delay time.Duration, lockout time.Duration) time.Time {
+	if failures == 0 {
+		return time.Time{}
+	}
+	if failures >= maxFailures {
+		return lastFailedAt.Add(lockout)
+	}
+	if delay == 0 {
+		return time.Time{}
+	}
+
+	backoff := delay
+	for i := int64(1); i < failures && backoff < lockout; i++ {
+		backoff *= 2
+	}
+	return lastFailedAt.Add(min(backoff, lockout))
+}
+
+// abortTooManyLoginAttempts answers a login that came before its retry time
+func abortTooManyLoginAttempts(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+	c.JSON(http.StatusTooManyRequests, errorResponse(errTooManyLoginAttempts))
+}
+
+// recordLoginAttempt adds a password check on the username to the login history
+func (server *Server) recordLoginAttempt(c *gin.Context, username string, success bool) error {
+	_, err := server.store.CreateLoginAttempt(c.Request.Context(), db.CreateLoginAttemptParams{
+		Username:  username,
+		ClientIp:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Success:   success,
+	})
+	return err
+}
+
+type listLoginHistoryRequest struct {
+	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=50"`
+	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+}
+
+// listLoginHistory lists the login attempts on the authenticated user, newest first
+func (server *Server) listLoginHistory(c *gin.Context) {
+	var req listLoginHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	attempts, err := server.store.ListLoginAttempts(c.Request.Context(), db.ListLoginAttemptsParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, attempts)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// expectLoginAllowed stubs the failed login counts of a client that may log in right away
+func expectLoginAllowed(store *mockdb.MockStore) {
+	store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.GetLoginFailuresByUsernameRow{}, nil)
+	store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).Return(db.GetLoginFailuresByClientIPRow{}, nil)
+}
+
+func TestLoginBackoff(t *testing.T) {
+	lastFailedAt := time.Now()
+
+	require.Zero(t, loginBackoff(0, lastFailedAt, 5, time.Second, time.Minute))
+	require.Equal(t, lastFailedAt.Add(time.Second), loginBackoff(1, lastFailedAt, 5, time.Second, time.Minute))
+	require.Equal(t, lastFailedAt.Add(2*time.Second), loginBackoff(2, lastFailedAt, 5, time.Second, time.Minute))
+	require.Equal(t, lastFailedAt.Add(8*time.Second), loginBackoff(4, lastFailedAt, 5, time.Second, time.Minute))
+
+	// the lockout starts at the limit and the delay never grows past it
+	require.Equal(t, lastFailedAt.Add(time.Minute), loginBackoff(5, lastFailedAt, 5, time.Second, time.Minute))
+	require.Equal(t, lastFailedAt.Add(time.Minute), loginBackoff(99, lastFailedAt, 100, time.Second, time.Minute))
+
+	// without a delay only the lockout applies
+	require.Zero(t, loginBackoff(4, lastFailedAt, 5, 0, time.Minute))
+	require.Equal(t, lastFailedAt.Add(time.Minute), loginBackoff(5, lastFailedAt, 5, 0, time.Minute))
+}
+
+func TestListLoginHistoryAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	attempts := []db.LoginAttempt{
+		{ID: 2, Username: user.Username, ClientIp: "192.0.2.1", Success: true, CreatedAt: time.Now()},
+		{ID: 1, Username: user.Username, ClientIp: "192.0.2.2", Success: false, CreatedAt: time.Now().Add(-time.Minute)},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListLoginAttemptsParams{
+					Username: user.Username,
+					Limit:    5,
+					Offset:   5,
+				}
+				store.EXPECT().ListLoginAttempts(gomock.Any(), gomock.Eq(arg)).Times(1).Return(attempts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp []db.LoginAttempt
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp, len(attempts))
+				require.Equal(t, attempts[0].ID, rsp[0].ID)
+				require.False(t, rsp[1].Success)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "?page_size=100",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginAttempts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListLoginAttempts(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/login_history"+tc.query, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
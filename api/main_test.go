@@ -6,18 +6,63 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/fx"
+	"github.com/hanifsyahsn/simple_bank/notifier"
+	"github.com/hanifsyahsn/simple_bank/token"
 	"github.com/hanifsyahsn/simple_bank/util"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func newTestConfig() util.Config {
+	return util.Config{
+		TokenType:                  token.TypePaseto,
+		TokenIssuer:                "simple_bank",
+		TokenAudience:              "simple_bank",
+		TokenSymmetricKey:          util.RandomString(32),
+		AccessTokenDuration:        time.Minute,
+		RefreshTokenDuration:       time.Hour,
+		TokenDenylist:              token.DenylistMemory,
+		PasswordResetTokenDuration: time.Minute,
+		PasswordHashAlgorithm:      util.PasswordHashArgon2id,
+		Argon2Memory:               util.DefaultArgon2idParams.Memory,
+		Argon2Iterations:           util.DefaultArgon2idParams.Iterations,
+		Argon2Parallelism:          util.DefaultArgon2idParams.Parallelism,
+		BcryptCost:                 bcrypt.MinCost,
+		PasswordMinLength:          6,
+		PasswordMaxLength:          64,
+		PasswordDisallowUserInfo:   true,
+		BreachedPasswordsFile:      "../util/breached_passwords.txt",
+		LoginMaxFailedAttempts:     5,
+		LoginMaxFailedAttemptsIP:   20,
+		LoginFailureDelay:          time.Second,
+		LoginLockoutDuration:       time.Minute,
+		Notifier:                   notifier.NotifierMemory,
+		VerifyEmailURL:             "http://localhost:8080/verify_email",
+		VerifyEmailDuration:        time.Hour,
+		TwoFactorIssuer:            "simple_bank",
+		TwoFactorEncryptionKey:     util.RandomString(32),
+		TwoFactorChallengeDuration: time.Minute,
+		FxRateProvider:             fx.ProviderStatic,
+		FxRatesFile:                "../fx/rates.json",
+		FxQuoteDuration:            time.Minute,
+		StepUpThresholds:           "USD:1000,EUR:1000,CAD:1000",
+		StepUpDuration:             time.Minute,
+		HoldDuration:               time.Hour,
+	}
+}
+
+// newTestServer creates a server for the tests, a mock store also accepts the tokens made by addAuthorization
+// unless the test expects TouchSession itself first
 func newTestServer(t *testing.T, store db.Store) *Server {
-	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+	if mockStore, ok := store.(*mockdb.MockStore); ok {
+		mockStore.EXPECT().TouchSession(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(touchTestSession)
 	}
 
-	server, err := NewServer(store, config)
+	server, err := NewServer(store, newTestConfig())
 	require.NoError(t, err)
 
 	return server
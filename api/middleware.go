@@ -1,22 +1,41 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
 	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
 )
 
 const (
 	authorizationKey        = "authorization"
 	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+// errRevokedSession is returned for tokens of a session that was revoked, logged out or has expired
+var errRevokedSession = errors.New("session has been revoked")
+
+// errNotAccessToken is returned for bearer tokens that are not the access token of a session, like refresh tokens
+var errNotAccessToken = errors.New("token is not an access token")
+
+// errInvalidAPIKey is returned for API keys that are malformed, unknown or revoked
+var errInvalidAPIKey = errors.New("invalid api key")
+
+// authMiddleware authenticates the bearer token or API key of the request, both end up as the same
+// authorization payload. Bearer tokens must be access tokens bound to a session, they are only accepted
+// while the session is active and was issued after the last password change, and mark the session as used,
+// just like API keys
+func authMiddleware(tokenMaker token.Maker, denylist token.Denylist, store db.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authorizationHeader := c.GetHeader(authorizationKey)
 		if len(authorizationHeader) == 0 {
@@ -32,21 +51,180 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		var payload *token.Payload
+		var ok bool
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
+		switch authorizationType {
+		case authorizationTypeBearer:
+			payload, ok = authenticateAccessToken(c, tokenMaker, denylist, store, fields[1])
+		case authorizationTypeAPIKey:
+			payload, ok = authenticateAPIKey(c, store, fields[1])
+		default:
 			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
+		if !ok {
+			return
+		}
+
+		c.Set(authorizationPayloadKey, payload)
+		c.Next()
+	}
+}
+
+// authenticateAccessToken verifies a bearer token, it aborts the request and returns false when the token is not accepted
+func authenticateAccessToken(c *gin.Context, tokenMaker token.Maker, denylist token.Denylist, store db.Store, accessToken string) (*token.Payload, bool) {
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+
+	revoked, err := denylist.IsRevoked(c.Request.Context(), payload)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	if revoked {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return nil, false
+	}
+
+	if payload.TokenType != token.TokenTypeAccess || payload.SessionID == uuid.Nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errNotAccessToken))
+		return nil, false
+	}
+
+	session, err := store.TouchSession(c.Request.Context(), payload.SessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errRevokedSession))
+			return nil, false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	if session.IsBlocked || session.Username != payload.Username || time.Now().After(session.ExpiresAt) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errRevokedSession))
+		return nil, false
+	}
+
+	// the issue time of a JWT only has whole seconds, so the password change is compared at the same granularity,
+	// the sessions blocked along with the change cover tokens issued earlier in that second
+	if payload.IssuedAt.Truncate(time.Second).Before(session.PasswordChangedAt.Truncate(time.Second)) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return nil, false
+	}
+
+	return payload, true
+}
+
+// authenticateAPIKey looks up an API key by its prefix and hash, it aborts the request and returns false
+// when the key is not accepted. The payload carries the role of the owner and the scopes of the key
+func authenticateAPIKey(c *gin.Context, store db.Store, key string) (*token.Payload, bool) {
+	prefix, ok := util.ParseAPIKeyPrefix(key)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return nil, false
+	}
+
+	apiKey, err := store.UseAPIKey(c.Request.Context(), db.UseAPIKeyParams{
+		Prefix:  prefix,
+		KeyHash: util.HashSecureToken(key),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+			return nil, false
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	payload := &token.Payload{
+		Username: apiKey.Username,
+		Role:     apiKey.Role,
+		IssuedAt: apiKey.CreatedAt,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}
+	return payload, true
+}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+// requireScope lets the request through only when it may use the scope, which users always may,
+// it must run after authMiddleware
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !authPayload.HasScope(scope) {
+			err := fmt.Errorf("api key does not have the %q scope", scope)
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireUserToken keeps API keys out of the endpoints that manage the user itself, such as its password,
+// sessions and API keys, it must run after authMiddleware
+func requireUserToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		if authPayload.APIKeyID != 0 {
+			err := errors.New("api keys cannot access this resource")
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireRole lets the request through only when the authenticated user has one of the roles,
+// it must run after authMiddleware
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		for _, role := range roles {
+			if authPayload.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("role %q is not allowed to access this resource", authPayload.Role)
+		c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
+
+// requireVerifiedEmail lets the request through only when the authenticated user has verified its email,
+// it lets every request through when verification is not required and must run after authMiddleware
+func requireVerifiedEmail(store db.Store, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := store.GetUser(c.Request.Context(), authPayload.Username)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if !user.IsEmailVerified {
+			err := errors.New("email address has not been verified")
+			c.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
 
-		c.Set(authorizationPayloadKey, payload)
 		c.Next()
 	}
 }
+
+// canReadAnyAccount reports whether the role may read the accounts and ledgers of every user
+func canReadAnyAccount(role string) bool {
+	return role == util.AdminRole || role == util.AuditorRole
+}
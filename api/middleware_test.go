@@ -1,27 +1,75 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
 	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
 	"github.com/stretchr/testify/require"
 )
 
+// testSessions maps the sessions of the tokens made by addAuthorization to their users
+var testSessions sync.Map
+
+// touchTestSession stands in for TouchSession with the active session of a token made by addAuthorization
+func touchTestSession(_ context.Context, id uuid.UUID) (db.TouchSessionRow, error) {
+	username, ok := testSessions.Load(id)
+	if !ok {
+		return db.TouchSessionRow{}, sql.ErrNoRows
+	}
+
+	session := db.TouchSessionRow{
+		ID:         id,
+		Username:   username.(string),
+		ExpiresAt:  time.Now().Add(time.Hour),
+		CreatedAt:  time.Now(),
+		LastUsedAt: time.Now(),
+	}
+	return session, nil
+}
+
+// touchedSession is what TouchSession returns for the session of a user whose password has never been changed
+func touchedSession(session db.Session) db.TouchSessionRow {
+	return db.TouchSessionRow{
+		ID:           session.ID,
+		Username:     session.Username,
+		RefreshToken: session.RefreshToken,
+		UserAgent:    session.UserAgent,
+		ClientIp:     session.ClientIp,
+		IsBlocked:    session.IsBlocked,
+		ExpiresAt:    session.ExpiresAt,
+		CreatedAt:    session.CreatedAt,
+		LastUsedAt:   session.LastUsedAt,
+	}
+}
+
 func addAuthorization(
 	t *testing.T,
 	request *http.Request,
 	tokenMaker token.Maker,
 	authorizationType string,
 	username string,
+	role string,
 	duration time.Duration,
 ) {
-	tok, err := tokenMaker.CreateToken(username, duration)
+	sessionID := uuid.New()
+	testSessions.Store(sessionID, username)
+
+	tok, payload, err := tokenMaker.CreateSessionToken(username, role, sessionID, token.TokenTypeAccess, duration)
 	require.NoError(t, err)
+	require.NotEmpty(t, payload)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, tok)
 	request.Header.Add(authorizationKey, authorizationHeader)
@@ -36,7 +84,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "OK",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
@@ -53,7 +101,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "Unsupported Authorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "unsupported", "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, "unsupported", "user", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -62,7 +110,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "Invalid Authorization Format",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, "", "user", time.Minute)
+				addAuthorization(t, request, tokenMaker, "", "user", util.DepositorRole, time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -71,7 +119,7 @@ func TestAuthMiddleware(t *testing.T) {
 		{
 			name: "Expired Token",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", -time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, -time.Minute)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusUnauthorized, recorder.Code)
@@ -82,12 +130,15 @@ func TestAuthMiddleware(t *testing.T) {
 	for i := range testCases {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
 
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.denylist, server.store),
 				func(context *gin.Context) {
 					context.JSON(http.StatusOK, gin.H{})
 				})
@@ -102,3 +153,513 @@ func TestAuthMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddlewareRevokedToken(t *testing.T) {
+	testCases := []struct {
+		name          string
+		revoke        func(t *testing.T, denylist token.Denylist, payload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			revoke: func(t *testing.T, denylist token.Denylist, payload *token.Payload) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Revoked Token",
+			revoke: func(t *testing.T, denylist token.Denylist, payload *token.Payload) {
+				err := denylist.RevokeToken(context.Background(), payload)
+				require.NoError(t, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Revoked All Tokens",
+			revoke: func(t *testing.T, denylist token.Denylist, payload *token.Payload) {
+				err := denylist.RevokeAllTokens(context.Background(), payload.Username, time.Now())
+				require.NoError(t, err)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server := newTestServer(t, mockdb.NewMockStore(ctrl))
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.denylist, server.store),
+				func(context *gin.Context) {
+					context.JSON(http.StatusOK, gin.H{})
+				})
+
+			sessionID := uuid.New()
+			testSessions.Store(sessionID, "user")
+
+			tok, payload, err := server.tokenMaker.CreateSessionToken("user", util.DepositorRole, sessionID, token.TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+			tc.revoke(t, server.denylist, payload)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", authPath, nil)
+			require.NoError(t, err)
+
+			request.Header.Add(authorizationKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tok))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareSession(t *testing.T) {
+	session := touchedSession(randomUserSession("user"))
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RevokedSession",
+			buildStubs: func(store *mockdb.MockStore) {
+				blocked := session
+				blocked.IsBlocked = true
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(blocked, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := session
+				expired.ExpiresAt = time.Now().Add(-time.Second)
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(expired, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionOfOtherUser",
+			buildStubs: func(store *mockdb.MockStore) {
+				other := session
+				other.Username = "other"
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(other, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChangedAfterIssue",
+			buildStubs: func(store *mockdb.MockStore) {
+				changed := session
+				changed.PasswordChangedAt = time.Now().Add(2 * time.Second)
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(changed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordChangedInSecondOfIssue",
+			buildStubs: func(store *mockdb.MockStore) {
+				// the token is issued right after the change, its issue time loses the fraction of the second
+				changed := session
+				changed.PasswordChangedAt = time.Now()
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(changed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.TouchSessionRow{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TouchSession(gomock.Any(), gomock.Any()).Times(1).Return(db.TouchSessionRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.denylist, server.store),
+				func(context *gin.Context) {
+					context.JSON(http.StatusOK, gin.H{})
+				})
+
+			tok, _, err := server.tokenMaker.CreateSessionToken("user", util.DepositorRole, session.ID, token.TokenTypeAccess, time.Minute)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", authPath, nil)
+			require.NoError(t, err)
+
+			request.Header.Add(authorizationKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tok))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareTokenType(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		createToken   func(t *testing.T, tokenMaker token.Maker) string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "AccessToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				sessionID := uuid.New()
+				testSessions.Store(sessionID, user.Username)
+
+				tok, _, err := tokenMaker.CreateSessionToken(user.Username, util.DepositorRole, sessionID, token.TokenTypeAccess, time.Minute)
+				require.NoError(t, err)
+				return tok
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshToken",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				sessionID := uuid.New()
+				testSessions.Store(sessionID, user.Username)
+
+				tok, _, err := tokenMaker.CreateSessionToken(user.Username, util.DepositorRole, sessionID, token.TokenTypeRefresh, time.Hour)
+				require.NoError(t, err)
+				return tok
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TokenWithoutSession",
+			createToken: func(t *testing.T, tokenMaker token.Maker) string {
+				tok, _, err := tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				return tok
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d", account.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			request.Header.Set(authorizationKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tc.createToken(t, server.tokenMaker)))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAuthMiddlewareAPIKey(t *testing.T) {
+	apiKey, key := randomAPIKey(t, "user", util.ScopeAccountsRead)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(request *http.Request, store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(request *http.Request, store *mockdb.MockStore) {
+				addAPIKeyAuthorization(request, store, apiKey, key, util.AuditorRole)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, apiKey.Username, payload.Username)
+				require.Equal(t, util.AuditorRole, payload.Role)
+				require.Equal(t, apiKey.ID, payload.APIKeyID)
+				require.Equal(t, apiKey.Scopes, payload.Scopes)
+			},
+		},
+		{
+			name: "MalformedKey",
+			setupAuth: func(request *http.Request, store *mockdb.MockStore) {
+				store.EXPECT().UseAPIKey(gomock.Any(), gomock.Any()).Times(0)
+				request.Header.Set(authorizationKey, "ApiKey "+util.RandomString(43))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownOrRevokedKey",
+			setupAuth: func(request *http.Request, store *mockdb.MockStore) {
+				store.EXPECT().UseAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.UseAPIKeyRow{}, sql.ErrNoRows)
+				request.Header.Set(authorizationKey, "ApiKey "+key)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(request *http.Request, store *mockdb.MockStore) {
+				store.EXPECT().UseAPIKey(gomock.Any(), gomock.Any()).Times(1).Return(db.UseAPIKeyRow{}, sql.ErrConnDone)
+				request.Header.Set(authorizationKey, "ApiKey "+key)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			var payload *token.Payload
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.denylist, server.store),
+				func(context *gin.Context) {
+					payload = context.MustGet(authorizationPayloadKey).(*token.Payload)
+					context.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", authPath, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(request, store)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, payload)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "User",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "APIKeyWithScope",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				apiKey, key := randomAPIKey(t, "user", util.ScopeAccountsRead, util.ScopeTransfersWrite)
+				addAPIKeyAuthorization(request, store, apiKey, key, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "APIKeyWithoutScope",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker, store *mockdb.MockStore) {
+				apiKey, key := randomAPIKey(t, "user", util.ScopeAccountsRead)
+				addAPIKeyAuthorization(request, store, apiKey, key, util.DepositorRole)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.denylist, server.store),
+				requireScope(util.ScopeTransfersWrite),
+				func(context *gin.Context) {
+					context.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", authPath, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker, store)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	testCases := []struct {
+		name          string
+		required      bool
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "NotRequired",
+			required: false,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "Verified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("user")).Times(1).
+					Return(db.User{Username: "user", IsEmailVerified: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotVerified",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq("user")).Times(1).
+					Return(db.User{Username: "user"}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalError",
+			required: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			authPath := "/auth"
+			server.router.GET(
+				authPath,
+				authMiddleware(server.tokenMaker, server.denylist, server.store),
+				requireVerifiedEmail(store, tc.required),
+				func(context *gin.Context) {
+					context.JSON(http.StatusOK, gin.H{})
+				})
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", authPath, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
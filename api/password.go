@@ -0,0 +1,246 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/notifier"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+// resetTokenSize is the number of random bytes of a password reset token
+const resetTokenSize = 32
+
+// maxPasswordResetTokens is how many password reset tokens a user is sent within the lifetime of a token
+const maxPasswordResetTokens = 3
+
+var errTooManyPasswordResetTokens = errors.New("too many password reset tokens requested")
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+func (server *Server) changePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			uve := util.ValidatorError(ve)
+			c.JSON(http.StatusBadRequest, uve)
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	retryAfter, err := server.loginRetryAfter(c.Request.Context(), authPayload.Username, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		abortTooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	user, err := server.store.GetUser(c.Request.Context(), authPayload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// a wrong old password counts towards the login lockout, so a stolen access token cannot guess it
+	passwordErr := server.passwordHasher.CheckPasswordHash(req.OldPassword, user.HashedPassword)
+	err = server.recordLoginAttempt(c, user.Username, passwordErr == nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if passwordErr != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(passwordErr))
+		return
+	}
+
+	if !server.checkPasswordPolicy(c, "NewPassword", req.NewPassword, user) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.UpdatePasswordTx(c.Request.Context(), db.UpdatePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.revokeTokensBeforePasswordChange(c, user)
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword sends a password reset token to the user with the email, the response is the same
+// whether a user has the email or not so it cannot be used to find out who has an account
+func (server *Server) forgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.Status(http.StatusAccepted)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// failures past this point only happen for existing users, answering them differently would give the user away
+	err = server.sendPasswordResetToken(c.Request.Context(), user)
+	if err != nil {
+		log.Printf("cannot send password reset token to user %s: %v", user.Username, err)
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// sendPasswordResetToken stores a new password reset token of the user and emails it, at most
+// maxPasswordResetTokens are sent within the lifetime of a token so the endpoint cannot flood an inbox
+func (server *Server) sendPasswordResetToken(ctx context.Context, user db.User) error {
+	sent, err := server.store.CountPasswordResetTokens(ctx, db.CountPasswordResetTokensParams{
+		Username: user.Username,
+		Since:    time.Now().Add(-server.config.PasswordResetTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+	if sent >= maxPasswordResetTokens {
+		return errTooManyPasswordResetTokens
+	}
+
+	resetToken, err := util.GenerateSecureToken(resetTokenSize)
+	if err != nil {
+		return err
+	}
+
+	arg := db.CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecureToken(resetToken),
+		ExpiresAt: time.Now().Add(server.config.PasswordResetTokenDuration),
+	}
+
+	stored, err := server.store.CreatePasswordResetToken(ctx, arg)
+	if err != nil {
+		return err
+	}
+
+	return server.notifier.Send(ctx, notifier.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this token to reset the password of %s: %s\nIt expires at %s.",
+			user.Username, resetToken, stored.ExpiresAt.Format(time.RFC1123)),
+	})
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+func (server *Server) resetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			uve := util.ValidatorError(ve)
+			c.JSON(http.StatusBadRequest, uve)
+			return
+		}
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(c.Request.Context(), db.ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(req.Token),
+		HashedPassword: hashedPassword,
+		BeforeUpdate: func(user db.User) error {
+			return server.passwordPolicy.Check(req.NewPassword, user.Username, user.Email)
+		},
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidResetToken) {
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		var policyErr *util.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, util.PasswordPolicyFieldError("NewPassword", policyErr))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.revokeTokensBeforePasswordChange(c, user)
+}
+
+// checkPasswordPolicy answers with the rule of the password policy a new password of the user breaks,
+// reporting whether the password may be used
+func (server *Server) checkPasswordPolicy(c *gin.Context, field string, password string, user db.User) bool {
+	err := server.passwordPolicy.Check(password, user.Username, user.Email)
+	if err != nil {
+		var policyErr *util.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			c.JSON(http.StatusBadRequest, util.PasswordPolicyFieldError(field, policyErr))
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	return true
+}
+
+// revokeTokensBeforePasswordChange makes authMiddleware reject every token issued with the old password,
+// the sessions have already been blocked along with the password change. The time is cut to whole seconds
+// like the issue time of a JWT, so a token issued right after the change in the same second stays valid
+func (server *Server) revokeTokensBeforePasswordChange(c *gin.Context, user db.User) {
+	err := server.denylist.RevokeAllTokens(c.Request.Context(), user.Username, user.PasswordChangedAt.Truncate(time.Second))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
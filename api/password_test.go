@@ -0,0 +1,543 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/notifier"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUser(t)
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UpdatePasswordTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NoError(t, util.CheckPasswordHash(newPassword, arg.HashedPassword))
+
+						updated := user
+						updated.HashedPassword = arg.HashedPassword
+						// a second later than the old token, tokens of the same second are left to the blocked sessions
+						updated.PasswordChangedAt = time.Now().Add(time.Second)
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.True(t, revoked)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now(), rsp.PasswordChangedAt, time.Second)
+			},
+		},
+		{
+			name: "IncorrectOldPassword",
+			body: gin.H{
+				"old_password": "incorrect",
+				"new_password": newPassword,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  false,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{
+				"old_password": password,
+				"new_password": "abc",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"NewPassword":"NewPassword must be at least 6 characters"}`, recorder.Body.String())
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "BreachedNewPassword",
+			body: gin.H{
+				"old_password": password,
+				"new_password": "Password1",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"NewPassword":"NewPassword has appeared in a data breach, choose another one"}`, recorder.Body.String())
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "LockedOut",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{FailedAttempts: 5, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdatePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{
+				"old_password": password,
+				"new_password": newPassword,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			// a token issued with the old password
+			_, oldPayload, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPatch, "/users/password", bytes.NewReader(body))
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+
+			revoked, err := server.denylist.IsRevoked(context.Background(), oldPayload)
+			require.NoError(t, err)
+			tc.checkResponse(t, recorder, revoked)
+		})
+	}
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore, tokenHash *string)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().CountPasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CountPasswordResetTokensParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now().Add(-time.Minute), arg.Since, time.Second)
+						return maxPasswordResetTokens - 1, nil
+					})
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						*tokenHash = arg.TokenHash
+						return db.PasswordResetToken{
+							ID:        1,
+							Username:  arg.Username,
+							TokenHash: arg.TokenHash,
+							ExpiresAt: arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Len(t, messages, 1)
+
+				// the token is only in the message, the store only sees its hash
+				fields := strings.Fields(messages[0].Body)
+				var found bool
+				for _, field := range fields {
+					if util.HashSecureToken(field) == tokenHash {
+						found = true
+					}
+				}
+				require.True(t, found)
+				require.NotContains(t, messages[0].Body, tokenHash)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "TooManyResetTokens",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().CountPasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).Return(int64(maxPasswordResetTokens), nil)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "InternalErrorForExistingUser",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+				store.EXPECT().CountPasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(db.User{}, sql.ErrConnDone)
+				store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, messages)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockStore, tokenHash *string) {
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, messages []notifier.Message, tokenHash string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			var tokenHash string
+			tc.buildStubs(store, &tokenHash)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+
+			messages := server.notifier.(*notifier.MemoryNotifier).Messages(user.Email)
+			tc.checkResponse(t, recorder, messages, tokenHash)
+		})
+	}
+}
+
+func TestForgotPasswordAPINotifierError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, _ := randomUser(t)
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).Times(1).Return(user, nil)
+	store.EXPECT().CountPasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+	store.EXPECT().CreatePasswordResetToken(gomock.Any(), gomock.Any()).Times(1).Return(db.PasswordResetToken{}, nil)
+
+	server := newTestServer(t, store)
+	server.notifier = failingNotifier{}
+
+	body, err := json.Marshal(gin.H{"email": user.Email})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users/password/forgot", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	// answered like an unknown email, so a failing notifier does not give the account away
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	resetToken := util.RandomString(43)
+	newPassword := util.RandomString(8)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+						require.Equal(t, util.HashSecureToken(resetToken), arg.TokenHash)
+						require.NoError(t, arg.BeforeUpdate(user))
+						require.NoError(t, util.CheckPasswordHash(newPassword, arg.HashedPassword))
+
+						updated := user
+						updated.HashedPassword = arg.HashedPassword
+						// a second later than the old token, tokens of the same second are left to the blocked sessions
+						updated.PasswordChangedAt = time.Now().Add(time.Second)
+						return updated, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrInvalidResetToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": newPassword,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": "abc",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+						return db.User{}, arg.BeforeUpdate(user)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"NewPassword":"NewPassword must be at least 6 characters"}`, recorder.Body.String())
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "NewPasswordContainsUsername",
+			body: gin.H{
+				"token":        resetToken,
+				"new_password": "my" + user.Username + "1",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.ResetPasswordTxParams) (db.User, error) {
+						return db.User{}, arg.BeforeUpdate(user)
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"NewPassword":"NewPassword must not contain the username"}`, recorder.Body.String())
+				require.False(t, revoked)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			_, oldPayload, err := server.tokenMaker.CreateToken(user.Username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/password/reset", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+
+			revoked, err := server.denylist.IsRevoked(context.Background(), oldPayload)
+			require.NoError(t, err)
+			tc.checkResponse(t, recorder, revoked)
+		})
+	}
+}
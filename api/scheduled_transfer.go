@@ -0,0 +1,306 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	// Schedule is a cron expression for a recurring transfer, it is left out for a one-off transfer
+	Schedule string `json:"schedule"`
+	// StartAt is when a one-off transfer runs, a recurring transfer first runs at its next occurrence after it
+	StartAt *time.Time `json:"start_at"`
+}
+
+func (server *Server) createScheduledTransfer(c *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	nextRunAt, err := firstRunAt(req.Schedule, req.StartAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, valid := server.ownedAccount(c, req.FromAccountID)
+	if !valid || !server.matchingCurrency(c, fromAccount, req.Currency) {
+		return
+	}
+
+	toAccount, valid := server.validAccount(c, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+	if toAccount.Owner == db.SystemUsername {
+		err := errors.New("cannot transfer to a settlement account, use a withdrawal instead")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// nobody is around to confirm a run, so an amount above the step-up threshold is confirmed when it is scheduled
+	if !server.stepUpConfirmed(c, req.Currency, req.Amount) {
+		return
+	}
+
+	scheduled, err := server.store.CreateScheduledTransfer(c.Request.Context(), db.CreateScheduledTransferParams{
+		Owner:         fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        req.Amount,
+		Schedule:      sql.NullString{String: req.Schedule, Valid: req.Schedule != ""},
+		NextRunAt:     sql.NullTime{Time: nextRunAt, Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, scheduled)
+}
+
+// firstRunAt works out when a scheduled transfer runs first, a start in the past counts as now
+func firstRunAt(schedule string, startAt *time.Time) (time.Time, error) {
+	from := time.Now()
+	if startAt != nil && startAt.After(from) {
+		from = *startAt
+	}
+
+	if schedule == "" {
+		if startAt == nil {
+			return from, errors.New("start_at is required for a one-off transfer")
+		}
+		return from, nil
+	}
+
+	s, err := util.ParseSchedule(schedule)
+	if err != nil {
+		return from, fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+	return s.Next(from), nil
+}
+
+type listScheduledTransfersRequest struct {
+	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=10"`
+	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+}
+
+func (server *Server) listScheduledTransfers(c *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduled, err := server.store.ListScheduledTransfers(c.Request.Context(), db.ListScheduledTransfersParams{
+		Owner:  authPayload.Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+type getScheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getScheduledTransfer(c *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, valid := server.ownedScheduledTransfer(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount   *int64  `json:"amount" binding:"omitempty,gt=0"`
+	Schedule *string `json:"schedule" binding:"omitempty,min=1"`
+	// Status pauses a transfer with suspended and resumes it with active
+	Status *string `json:"status" binding:"omitempty,oneof=active suspended"`
+}
+
+func (server *Server) updateScheduledTransfer(c *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, valid := server.ownedScheduledTransfer(c, uri.ID)
+	if !valid || !pendingScheduledTransfer(c, scheduled) {
+		return
+	}
+
+	// a changed amount or a resumed transfer is confirmed like a new one
+	if req.Amount != nil || (req.Status != nil && *req.Status == db.ScheduledTransferStatusActive) {
+		amount := scheduled.Amount
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		fromAccount, valid := server.loadAccount(c, scheduled.FromAccountID)
+		if !valid || !server.stepUpConfirmed(c, fromAccount.Currency, amount) {
+			return
+		}
+	}
+
+	arg := db.UpdateScheduledTransferParams{
+		ID: scheduled.ID,
+	}
+	if req.Amount != nil {
+		arg.Amount = sql.NullInt64{Int64: *req.Amount, Valid: true}
+	}
+	if req.Status != nil {
+		arg.Status = sql.NullString{String: *req.Status, Valid: true}
+		if *req.Status == db.ScheduledTransferStatusActive {
+			arg.FailedAttempts = sql.NullInt32{Int32: 0, Valid: true}
+		}
+	}
+
+	schedule := scheduled.Schedule.String
+	if req.Schedule != nil {
+		schedule = *req.Schedule
+		arg.Schedule = sql.NullString{String: schedule, Valid: true}
+	}
+	// a changed or resumed recurring transfer skips the occurrences it missed
+	if schedule != "" && (req.Schedule != nil || req.Status != nil) {
+		nextRunAt, err := firstRunAt(schedule, nil)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.NextRunAt = sql.NullTime{Time: nextRunAt, Valid: true}
+	}
+
+	scheduled, err := server.store.UpdateScheduledTransfer(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+func (server *Server) cancelScheduledTransfer(c *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, valid := server.ownedScheduledTransfer(c, uri.ID)
+	if !valid || !pendingScheduledTransfer(c, scheduled) {
+		return
+	}
+
+	scheduled, err := server.store.SetScheduledTransferState(c.Request.Context(), db.SetScheduledTransferStateParams{
+		ID:             scheduled.ID,
+		Status:         db.ScheduledTransferStatusCancelled,
+		FailedAttempts: scheduled.FailedAttempts,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+type listScheduledTransferRunsRequest struct {
+	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=10"`
+	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+}
+
+func (server *Server) listScheduledTransferRuns(c *gin.Context) {
+	var uri getScheduledTransferRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listScheduledTransferRunsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, valid := server.ownedScheduledTransfer(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	runs, err := server.store.ListScheduledTransferRuns(c.Request.Context(), db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// ownedScheduledTransfer loads a scheduled transfer and makes sure it belongs to the authenticated user
+func (server *Server) ownedScheduledTransfer(c *gin.Context, id int64) (db.ScheduledTransfer, bool) {
+	scheduled, err := server.store.GetScheduledTransfer(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return scheduled, false
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return scheduled, false
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if scheduled.Owner != authPayload.Username {
+		err := errors.New("scheduled transfer does not belong to authenticated user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return scheduled, false
+	}
+
+	return scheduled, true
+}
+
+// pendingScheduledTransfer makes sure a scheduled transfer has not completed or been cancelled
+func pendingScheduledTransfer(c *gin.Context, scheduled db.ScheduledTransfer) bool {
+	if scheduled.Status == db.ScheduledTransferStatusCompleted || scheduled.Status == db.ScheduledTransferStatusCancelled {
+		err := fmt.Errorf("scheduled transfer %d is %s", scheduled.ID, scheduled.Status)
+		c.JSON(http.StatusConflict, errorResponse(err))
+		return false
+	}
+	return true
+}
@@ -1,39 +1,92 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/fx"
+	"github.com/hanifsyahsn/simple_bank/notifier"
 	"github.com/hanifsyahsn/simple_bank/token"
 	"github.com/hanifsyahsn/simple_bank/util"
 	_ "github.com/lib/pq"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config           util.Config
+	store            db.Store
+	tokenMaker       token.Maker
+	keyRing          *token.KeyRing
+	denylist         token.Denylist
+	passwordHasher   util.PasswordHasher
+	passwordPolicy   *util.PasswordPolicy
+	rates            fx.RateProvider
+	notifier         notifier.Notifier
+	stepUpThresholds map[string]int64
+	router           *gin.Engine
+
+	dummyPasswordHashOnce sync.Once
+	dummyPasswordHash     string
 }
 
 func NewServer(store db.Store, config util.Config) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, keyRing, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %v", err)
 	}
+	denylist, err := newDenylist(config.TokenDenylist, store)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token denylist: %v", err)
+	}
+	passwordHasher, err := newPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %v", err)
+	}
+	passwordPolicy, err := newPasswordPolicy(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password policy: %v", err)
+	}
+	if len(config.TwoFactorEncryptionKey) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid two factor encryption key size: must be exactly %d characters", chacha20poly1305.KeySize)
+	}
+	rates, err := newRateProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create exchange rate provider: %v", err)
+	}
+	userNotifier, err := newNotifier(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create notifier: %v", err)
+	}
+	stepUpThresholds, err := util.ParseCurrencyAmounts(config.StepUpThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse step-up thresholds: %v", err)
+	}
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:           config,
+		store:            store,
+		tokenMaker:       tokenMaker,
+		keyRing:          keyRing,
+		denylist:         denylist,
+		passwordHasher:   passwordHasher,
+		passwordPolicy:   passwordPolicy,
+		rates:            rates,
+		notifier:         userNotifier,
+		stepUpThresholds: stepUpThresholds,
 	}
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		err = v.RegisterValidation("currency", validCurrency)
 		if err != nil {
 			return nil, fmt.Errorf("cannot register validation: %v", err)
 		}
+		err = v.RegisterValidation("scope", validScope)
+		if err != nil {
+			return nil, fmt.Errorf("cannot register validation: %v", err)
+		}
 	}
 
 	server.setupRouter()
@@ -45,18 +98,179 @@ func (server *Server) setupRouter() {
 
 	router.POST("/users", server.createUser)
 	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login/2fa", server.loginTwoFactor)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.GET("/verify_email", server.verifyEmail)
+	router.POST("/tokens/renew_access", server.renewAccessToken)
+	router.GET("/.well-known/jwks.json", server.getJWKS)
+
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.denylist, server.store))
+	verifiedEmail := requireVerifiedEmail(server.store, server.config.RequireVerifiedEmail)
+	userToken := requireUserToken()
+	accountsRead := requireScope(util.ScopeAccountsRead)
+	accountsWrite := requireScope(util.ScopeAccountsWrite)
+	transfersRead := requireScope(util.ScopeTransfersRead)
+	transfersWrite := requireScope(util.ScopeTransfersWrite)
+	banker := requireRole(util.BankerRole, util.AdminRole)
+
+	authRoutes.POST("/users/logout", userToken, server.logoutUser)
+	authRoutes.POST("/users/logout_all", userToken, server.logoutAll)
+	authRoutes.GET("/users/login_history", userToken, server.listLoginHistory)
+	authRoutes.GET("/users/sessions", userToken, server.listSessions)
+	authRoutes.DELETE("/users/sessions/:id", userToken, server.revokeSession)
+	authRoutes.POST("/users/api_keys", userToken, server.createAPIKey)
+	authRoutes.GET("/users/api_keys", userToken, server.listAPIKeys)
+	authRoutes.DELETE("/users/api_keys/:id", userToken, server.revokeAPIKey)
+	authRoutes.PATCH("/users/password", userToken, server.changePassword)
+	authRoutes.POST("/users/step_up", userToken, server.createStepUpToken)
+	authRoutes.POST("/users/2fa/enroll", userToken, server.enrollTOTP)
+	authRoutes.POST("/users/2fa/verify", userToken, server.verifyTOTP)
+	authRoutes.POST("/users/verify_email", userToken, server.resendVerifyEmail)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	authRoutes.POST("/accounts", accountsWrite, verifiedEmail, server.createAccount)
+	authRoutes.GET("/accounts/:id", accountsRead, server.getAccount)
+	authRoutes.GET("/accounts", accountsRead, server.getAccounts)
+	authRoutes.GET("/accounts/:id/transfers", accountsRead, transfersRead, server.listAccountTransfers)
+	authRoutes.GET("/accounts/:id/entries", accountsRead, server.listAccountEntries)
+	authRoutes.POST("/accounts/:id/deposits", accountsWrite, banker, server.createDeposit)
+	authRoutes.POST("/accounts/:id/withdrawals", accountsWrite, banker, server.createWithdrawal)
+	authRoutes.POST("/accounts/:id/holds", accountsWrite, server.createHold)
+	authRoutes.GET("/accounts/:id/holds", accountsRead, server.listHolds)
+	authRoutes.GET("/accounts/:id/holds/:hold_id", accountsRead, server.getHold)
+	authRoutes.POST("/accounts/:id/holds/:hold_id/capture", accountsWrite, server.captureHold)
+	authRoutes.POST("/accounts/:id/holds/:hold_id/void", accountsWrite, server.voidHold)
+	authRoutes.POST("/accounts/:id/freeze", accountsWrite, requireRole(util.AdminRole), server.freezeAccount)
+	authRoutes.POST("/accounts/:id/unfreeze", accountsWrite, requireRole(util.AdminRole), server.unfreezeAccount)
 
-	authRoutes.POST("/accounts", server.createAccount)
-	authRoutes.GET("/accounts/:id", server.getAccount)
-	authRoutes.GET("/accounts", server.getAccounts)
+	authRoutes.POST("/transfers", transfersWrite, verifiedEmail, server.createTransfer)
+	authRoutes.POST("/transfers/batch", transfersWrite, verifiedEmail, server.createBatchTransfer)
+	authRoutes.GET("/transfers/:id", transfersRead, server.getTransfer)
+	authRoutes.POST("/transfers/:id/reverse", transfersWrite, server.reverseTransfer)
 
-	authRoutes.POST("/transfers", server.createTransfer)
+	authRoutes.POST("/pending_transfers/:id/confirm", transfersWrite, verifiedEmail, server.confirmPendingTransfer)
+
+	authRoutes.POST("/scheduled_transfers", transfersWrite, verifiedEmail, server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", transfersRead, server.listScheduledTransfers)
+	authRoutes.GET("/scheduled_transfers/:id", transfersRead, server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled_transfers/:id", transfersWrite, server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", transfersWrite, server.cancelScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", transfersRead, server.listScheduledTransferRuns)
+
+	authRoutes.GET("/fx/quote", transfersWrite, server.createFxQuote)
 
 	server.router = router
 }
 
+// newTokenMaker creates the token maker of the configured type, failing when its key material is invalid
+func newTokenMaker(config util.Config) (token.Maker, *token.KeyRing, error) {
+	switch config.TokenType {
+	case token.TypePaseto:
+		tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey, config.TokenIssuer, config.TokenAudience)
+		return tokenMaker, nil, err
+	case token.TypeJWT:
+		tokenMaker, err := token.NewJWTMaker(config.TokenSymmetricKey, config.TokenIssuer, config.TokenAudience)
+		return tokenMaker, nil, err
+	case token.TypeJWTPublic:
+		keyRing, err := token.LoadKeyRing(config.TokenSigningKeyID, config.TokenPrivateKeyFile, config.TokenVerifyingKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokenMaker, err := token.NewAsymmetricJWTMaker(keyRing, config.TokenIssuer, config.TokenAudience)
+		return tokenMaker, keyRing, err
+	case token.TypePasetoPublic:
+		keyRing, err := token.ParseKeyRing(config.TokenSigningKeyID, config.TokenSigningKey, config.TokenVerifyingKeys)
+		if err != nil {
+			return nil, nil, err
+		}
+		tokenMaker, err := token.NewPasetoPublicMaker(keyRing, config.TokenIssuer, config.TokenAudience)
+		return tokenMaker, keyRing, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported token type %q", config.TokenType)
+	}
+}
+
+func newDenylist(kind string, store db.Store) (token.Denylist, error) {
+	switch kind {
+	case token.DenylistMemory:
+		return token.NewMemoryDenylist(), nil
+	case token.DenylistPostgres:
+		return token.NewPostgresDenylist(store), nil
+	default:
+		return nil, fmt.Errorf("unsupported token denylist %q", kind)
+	}
+}
+
+func newPasswordHasher(config util.Config) (util.PasswordHasher, error) {
+	switch config.PasswordHashAlgorithm {
+	case util.PasswordHashArgon2id:
+		params := util.DefaultArgon2idParams
+		params.Memory = config.Argon2Memory
+		params.Iterations = config.Argon2Iterations
+		params.Parallelism = config.Argon2Parallelism
+		if params.Memory < 8*uint32(params.Parallelism) || params.Iterations == 0 || params.Parallelism == 0 {
+			return nil, errors.New("invalid argon2id parameters")
+		}
+		return util.NewArgon2idHasher(params), nil
+	case util.PasswordHashBcrypt:
+		return util.NewBcryptHasher(config.BcryptCost)
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", config.PasswordHashAlgorithm)
+	}
+}
+
+func newPasswordPolicy(config util.Config) (*util.PasswordPolicy, error) {
+	if config.PasswordMinLength < 1 || (config.PasswordMaxLength > 0 && config.PasswordMaxLength < config.PasswordMinLength) {
+		return nil, errors.New("invalid password length limits")
+	}
+	policy := &util.PasswordPolicy{
+		MinLength:        config.PasswordMinLength,
+		MaxLength:        config.PasswordMaxLength,
+		RequireUpper:     config.PasswordRequireUpper,
+		RequireLower:     config.PasswordRequireLower,
+		RequireDigit:     config.PasswordRequireDigit,
+		RequireSymbol:    config.PasswordRequireSymbol,
+		DisallowUserInfo: config.PasswordDisallowUserInfo,
+	}
+	if config.PasswordHashAlgorithm == util.PasswordHashBcrypt {
+		policy.MaxBytes = util.BcryptMaxPasswordBytes
+	}
+	if config.BreachedPasswordsFile != "" {
+		breached, err := util.LoadBreachedPasswords(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+	return policy, nil
+}
+
+func newRateProvider(config util.Config) (fx.RateProvider, error) {
+	switch config.FxRateProvider {
+	case fx.ProviderMemory:
+		return fx.NewMemoryRateProvider(), nil
+	case fx.ProviderStatic:
+		return fx.NewStaticRateProvider(config.FxRatesFile)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rate provider %q", config.FxRateProvider)
+	}
+}
+
+func newNotifier(config util.Config) (notifier.Notifier, error) {
+	switch config.Notifier {
+	case notifier.NotifierLog:
+		return notifier.NewLogNotifier(), nil
+	case notifier.NotifierMemory:
+		return notifier.NewMemoryNotifier(), nil
+	case notifier.NotifierFile:
+		return notifier.NewFileNotifier(config.NotifierFile), nil
+	case notifier.NotifierSMTP:
+		return notifier.NewSMTPNotifier(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.EmailSenderAddress)
+	default:
+		return nil, fmt.Errorf("unsupported notifier %q", config.Notifier)
+	}
+}
+
 func (server *Server) Start(address string) error {
 	return server.router.Run(address)
 }
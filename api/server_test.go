@@ -0,0 +1,214 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTokenMaker(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString([]byte(util.RandomString(32)))
+
+	testCases := []struct {
+		name        string
+		setupConfig func(config *util.Config)
+		checkResult func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error)
+	}{
+		{
+			name: "Paseto",
+			setupConfig: func(config *util.Config) {
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.PasetoMaker{}, tokenMaker)
+				require.Nil(t, keyRing)
+			},
+		},
+		{
+			name: "JWT",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypeJWT
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.JWTMaker{}, tokenMaker)
+				require.Nil(t, keyRing)
+			},
+		},
+		{
+			name: "JWTPublic",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypeJWTPublic
+				config.TokenSigningKeyID = "key-1"
+				config.TokenPrivateKeyFile, _ = writePrivateKey(t)
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.JWTMaker{}, tokenMaker)
+				require.NotNil(t, keyRing)
+			},
+		},
+		{
+			name: "JWTPublicMissingKeyFile",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypeJWTPublic
+				config.TokenSigningKeyID = "key-1"
+				config.TokenPrivateKeyFile = filepath.Join(t.TempDir(), "missing.pem")
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "PasetoPublic",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypePasetoPublic
+				config.TokenSigningKeyID = "key-1"
+				config.TokenSigningKey = seed
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &token.PasetoPublicMaker{}, tokenMaker)
+				require.NotNil(t, keyRing)
+			},
+		},
+		{
+			name: "PasetoInvalidKey",
+			setupConfig: func(config *util.Config) {
+				config.TokenSymmetricKey = util.RandomString(16)
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "JWTInvalidKey",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypeJWT
+				config.TokenSymmetricKey = util.RandomString(16)
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "PasetoPublicMissingKey",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypePasetoPublic
+				config.TokenSigningKeyID = "key-1"
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "UnsupportedType",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = "macaroon"
+			},
+			checkResult: func(t *testing.T, tokenMaker token.Maker, keyRing *token.KeyRing, err error) {
+				require.EqualError(t, err, `unsupported token type "macaroon"`)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			tc.setupConfig(&config)
+
+			tokenMaker, keyRing, err := newTokenMaker(config)
+			tc.checkResult(t, tokenMaker, keyRing, err)
+		})
+	}
+}
+
+func writePrivateKey(t *testing.T) (string, ed25519.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "token.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+	return path, publicKey
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	testCases := []struct {
+		name        string
+		setupConfig func(config *util.Config)
+		checkResult func(t *testing.T, hasher util.PasswordHasher, err error)
+	}{
+		{
+			name: "Argon2id",
+			setupConfig: func(config *util.Config) {
+			},
+			checkResult: func(t *testing.T, hasher util.PasswordHasher, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &util.Argon2idHasher{}, hasher)
+			},
+		},
+		{
+			name: "Bcrypt",
+			setupConfig: func(config *util.Config) {
+				config.PasswordHashAlgorithm = util.PasswordHashBcrypt
+			},
+			checkResult: func(t *testing.T, hasher util.PasswordHasher, err error) {
+				require.NoError(t, err)
+				require.IsType(t, &util.BcryptHasher{}, hasher)
+			},
+		},
+		{
+			name: "InvalidBcryptCost",
+			setupConfig: func(config *util.Config) {
+				config.PasswordHashAlgorithm = util.PasswordHashBcrypt
+				config.BcryptCost = 1
+			},
+			checkResult: func(t *testing.T, hasher util.PasswordHasher, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "InvalidArgon2idParams",
+			setupConfig: func(config *util.Config) {
+				config.Argon2Parallelism = 0
+			},
+			checkResult: func(t *testing.T, hasher util.PasswordHasher, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "UnsupportedAlgorithm",
+			setupConfig: func(config *util.Config) {
+				config.PasswordHashAlgorithm = "md5"
+			},
+			checkResult: func(t *testing.T, hasher util.PasswordHasher, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			config := newTestConfig()
+			tc.setupConfig(&config)
+
+			hasher, err := newPasswordHasher(config)
+			tc.checkResult(t, hasher, err)
+		})
+	}
+}
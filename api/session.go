@@ -0,0 +1,106 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+)
+
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current is true for the session of the token making the request
+	Current bool `json:"current"`
+}
+
+func newSessionResponse(session db.Session, currentSessionID uuid.UUID) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		Current:    session.ID == currentSessionID,
+	}
+}
+
+type listSessionsRequest struct {
+	PageSize int32 `form:"page_size,default=10" binding:"min=1,max=50"`
+	PageID   int32 `form:"page_id,default=1" binding:"min=1"`
+}
+
+// listSessions lists the active sessions of the authenticated user, most recently used first
+func (server *Server) listSessions(c *gin.Context) {
+	var req listSessionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	sessions, err := server.store.ListActiveSessions(c.Request.Context(), db.ListActiveSessionsParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		rsp[i] = newSessionResponse(session, authPayload.SessionID)
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+type revokeSessionRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// revokeSession blocks a session of the authenticated user, its refresh token and access tokens stop working
+func (server *Server) revokeSession(c *gin.Context) {
+	var req revokeSessionRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	sessionID := uuid.MustParse(req.ID)
+
+	session, err := server.store.GetSession(c.Request.Context(), sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if session.Username != authPayload.Username {
+		err := errors.New("session does not belong to authenticated user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	err = server.store.BlockSession(c.Request.Context(), session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
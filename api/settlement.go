@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+)
+
+type depositRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+// createDeposit credits money handed over at the counter to an account from the settlement account of its currency,
+// only bankers and admins may post it since it brings new money into the bank
+func (server *Server) createDeposit(c *gin.Context) {
+	var uri getAccountRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req depositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	idempotency, err := idempotencyParams(c, authPayload.Username, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if idempotency != nil && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+		return
+	}
+
+	account, valid := server.loadAccount(c, uri.ID)
+	if !valid || !server.matchingCurrency(c, account, req.Currency) {
+		return
+	}
+
+	result, err := server.store.DepositTx(c.Request.Context(), db.DepositTxParams{
+		AccountID:   account.ID,
+		Amount:      req.Amount,
+		Idempotency: idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+type withdrawalRequest struct {
+	Amount   int64  `json:"amount" binding:"required,gt=0"`
+	Currency string `json:"currency" binding:"required,currency"`
+}
+
+// createWithdrawal debits money paid out at the counter from an account to the settlement account of its currency,
+// only bankers and admins may post it
+func (server *Server) createWithdrawal(c *gin.Context) {
+	var uri getAccountRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req withdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	idempotency, err := idempotencyParams(c, authPayload.Username, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if idempotency != nil && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+		return
+	}
+
+	account, valid := server.loadAccount(c, uri.ID)
+	if !valid || !server.matchingCurrency(c, account, req.Currency) {
+		return
+	}
+
+	result, err := server.store.WithdrawTx(c.Request.Context(), db.WithdrawTxParams{
+		AccountID:   account.ID,
+		Amount:      req.Amount,
+		Idempotency: idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
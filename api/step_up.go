@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+)
+
+// maxStepUpAttempts is how many wrong passwords or codes a pending transfer accepts before it can no longer be confirmed
+const maxStepUpAttempts = 5
+
+// stepUpTokenHeader carries the step-up token of requests that move an amount above the step-up threshold
+// without being held as a pending transfer, like batch transfers, scheduled transfers and hold captures
+const stepUpTokenHeader = "Step-Up-Token"
+
+var errTwoFactorNotEnabled = errors.New("two factor authentication is not enabled")
+
+var errStepUpRequired = fmt.Errorf("amount is above the step-up threshold, send a step-up token from POST /users/step_up in the %s header", stepUpTokenHeader)
+
+var errInvalidStepUpToken = errors.New("step-up token is invalid or belongs to another session")
+
+// requiresStepUp reports whether a transfer of the amount must be confirmed again by its owner,
+// currencies without a configured threshold never require it
+func (server *Server) requiresStepUp(currency string, amount int64) bool {
+	threshold, ok := server.stepUpThresholds[currency]
+	return ok && amount > threshold
+}
+
+// stepUpConfirmed reports whether a request moving the amount may go ahead, an amount above the step-up threshold
+// needs a step-up token of the same session so API keys can never move it. It writes the error response otherwise
+func (server *Server) stepUpConfirmed(c *gin.Context, currency string, amount int64) bool {
+	if !server.requiresStepUp(currency, amount) {
+		return true
+	}
+
+	stepUpToken := c.GetHeader(stepUpTokenHeader)
+	if stepUpToken == "" {
+		c.JSON(http.StatusForbidden, errorResponse(errStepUpRequired))
+		return false
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	payload, err := server.tokenMaker.VerifyToken(stepUpToken)
+	if err != nil || payload.TokenType != token.TokenTypeStepUp || payload.Username != authPayload.Username ||
+		payload.SessionID == uuid.Nil || payload.SessionID != authPayload.SessionID {
+		c.JSON(http.StatusForbidden, errorResponse(errInvalidStepUpToken))
+		return false
+	}
+	return true
+}
+
+// addAmounts sums the amounts without overflowing, a sum that does not fit is math.MaxInt64
+func addAmounts(amounts ...int64) int64 {
+	var total int64
+	for _, amount := range amounts {
+		if amount > math.MaxInt64-total {
+			return math.MaxInt64
+		}
+		total += amount
+	}
+	return total
+}
+
+// validStepUpCredentials checks the password of the user, or its TOTP code once 2FA is enabled
+func (server *Server) validStepUpCredentials(ctx context.Context, user db.User, password string, code string) (bool, error) {
+	if code == "" {
+		return server.passwordHasher.CheckPasswordHash(password, user.HashedPassword) == nil, nil
+	}
+	if !user.IsTotpEnabled {
+		return false, errTwoFactorNotEnabled
+	}
+	return server.validTOTPCode(ctx, user, code)
+}
+
+type createStepUpTokenRequest struct {
+	Password string `json:"password" binding:"required_without=Code"`
+	// Code is the current TOTP code, it can be given instead of the password once 2FA is enabled
+	Code string `json:"code" binding:"omitempty,numeric,len=6"`
+}
+
+type createStepUpTokenResponse struct {
+	StepUpToken          string    `json:"step_up_token"`
+	StepUpTokenExpiresAt time.Time `json:"step_up_token_expires_at"`
+}
+
+// createStepUpToken confirms the password or TOTP code of the authenticated user again and returns a short-lived
+// step-up token for its session. Wrong passwords and codes count towards the login lockout
+func (server *Server) createStepUpToken(c *gin.Context) {
+	var req createStepUpTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	retryAfter, err := server.loginRetryAfter(c.Request.Context(), authPayload.Username, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		abortTooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	user, err := server.store.GetUser(c.Request.Context(), authPayload.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	valid, err := server.validStepUpCredentials(c.Request.Context(), user, req.Password, req.Code)
+	if err != nil {
+		if errors.Is(err, errTwoFactorNotEnabled) {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.recordLoginAttempt(c, user.Username, valid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		err := errors.New("invalid password or code")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	stepUpToken, stepUpPayload, err := server.tokenMaker.CreateSessionToken(user.Username, user.Role, authPayload.SessionID, token.TokenTypeStepUp, server.config.StepUpDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := createStepUpTokenResponse{
+		StepUpToken:          stepUpToken,
+		StepUpTokenExpiresAt: stepUpPayload.ExpiresAt,
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+// createPendingTransfer holds a high-value transfer until its owner confirms it with POST /pending_transfers/:id/confirm
+func (server *Server) createPendingTransfer(c *gin.Context, owner string, arg db.TransferTxParams, expiresAt time.Time) {
+	if arg.ToAmount == 0 {
+		arg.ToAmount = arg.Amount
+	}
+	if arg.ExchangeRate == "" {
+		arg.ExchangeRate = "1"
+	}
+
+	pending, err := server.store.CreatePendingTransferTx(c.Request.Context(), db.CreatePendingTransferTxParams{
+		CreatePendingTransferParams: db.CreatePendingTransferParams{
+			Owner:         owner,
+			FromAccountID: arg.FromAccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        arg.Amount,
+			ToAmount:      arg.ToAmount,
+			ExchangeRate:  arg.ExchangeRate,
+			QuoteID:       arg.QuoteID,
+			ExpiresAt:     expiresAt,
+		},
+		Idempotency: arg.Idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, arg.Idempotency, http.StatusAccepted) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, pending)
+}
+
+type getPendingTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type confirmPendingTransferRequest struct {
+	Password string `json:"password" binding:"required_without=Code"`
+	// Code is the current TOTP code, it can be given instead of the password once 2FA is enabled
+	Code string `json:"code" binding:"omitempty,numeric,len=6"`
+}
+
+// confirmPendingTransfer executes a pending transfer of the authenticated user once its password or TOTP code
+// is confirmed again. Wrong passwords and codes count towards the login lockout as well as the attempts of the pending transfer
+func (server *Server) confirmPendingTransfer(c *gin.Context) {
+	var uri getPendingTransferRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req confirmPendingTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	pending, err := server.store.GetPendingTransfer(c.Request.Context(), uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if pending.Owner != authPayload.Username {
+		err := errors.New("pending transfer does not belong to authenticated user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	retryAfter, err := server.loginRetryAfter(c.Request.Context(), pending.Owner, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		abortTooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	user, err := server.store.GetUser(c.Request.Context(), pending.Owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var checked, valid bool
+	result, err := server.store.ConfirmPendingTransferTx(c.Request.Context(), db.ConfirmPendingTransferTxParams{
+		ID:                pending.ID,
+		MaxFailedAttempts: maxStepUpAttempts,
+		CheckCredentials: func(db.PendingTransfer) (bool, error) {
+			var err error
+			valid, err = server.validStepUpCredentials(c.Request.Context(), user, req.Password, req.Code)
+			checked = err == nil
+			return valid, err
+		},
+	})
+	if checked {
+		if err := server.recordLoginAttempt(c, user.Username, valid); err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errTwoFactorNotEnabled):
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+		case errors.Is(err, db.ErrPendingTransferInvalidCredentials), errors.Is(err, db.ErrPendingTransferTooManyAttempts):
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+		case errors.Is(err, db.ErrPendingTransferConfirmed), errors.Is(err, db.ErrQuoteAlreadyUsed):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, db.ErrPendingTransferExpired), errors.Is(err, db.ErrInsufficientFunds):
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		case errors.Is(err, db.ErrAccountFrozen):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// addStepUpAuthorization authorizes the request like addAuthorization and adds a step-up token of the same session
+func addStepUpAuthorization(t *testing.T, request *http.Request, tokenMaker token.Maker, username string, role string) {
+	sessionID := uuid.New()
+	testSessions.Store(sessionID, username)
+
+	accessToken, _, err := tokenMaker.CreateSessionToken(username, role, sessionID, token.TokenTypeAccess, time.Minute)
+	require.NoError(t, err)
+	stepUpToken, _, err := tokenMaker.CreateSessionToken(username, role, sessionID, token.TokenTypeStepUp, time.Minute)
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+	request.Header.Set(stepUpTokenHeader, stepUpToken)
+}
+
+// expectConfirmPendingTransferTx stubs ConfirmPendingTransferTx like the store runs it: the credentials are checked
+// with the callback of the handler, and the result is returned once they are valid
+func expectConfirmPendingTransferTx(t *testing.T, store *mockdb.MockStore, pending db.PendingTransfer, result db.TransferTxResult, err error) {
+	store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.ConfirmPendingTransferTxParams) (db.TransferTxResult, error) {
+			require.Equal(t, pending.ID, arg.ID)
+			require.Equal(t, int32(maxStepUpAttempts), arg.MaxFailedAttempts)
+
+			valid, checkErr := arg.CheckCredentials(pending)
+			if checkErr != nil {
+				return db.TransferTxResult{}, checkErr
+			}
+			if !valid {
+				return db.TransferTxResult{}, db.ErrPendingTransferInvalidCredentials
+			}
+			return result, err
+		})
+}
+
+func TestConfirmPendingTransferAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		username      string
+		body          func(password string, code string) gin.H
+		buildStubs    func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKWithPassword",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectConfirmPendingTransferTx(t, store, pending, db.TransferTxResult{Transfer: db.Transfer{ID: 1, Amount: pending.Amount}}, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				var rsp db.TransferTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), rsp.Transfer.ID)
+			},
+		},
+		{
+			name: "OKWithTOTPCode",
+			body: func(password string, code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				expectConfirmPendingTransferTx(t, store, pending, db.TransferTxResult{}, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "TwoFactorNotEnabled",
+			body: func(password string, code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				user.IsTotpEnabled = false
+
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectConfirmPendingTransferTx(t, store, pending, db.TransferTxResult{}, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": "incorrect"}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectConfirmPendingTransferTx(t, store, pending, db.TransferTxResult{}, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  false,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyFailedAttempts",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrPendingTransferTooManyAttempts)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{FailedAttempts: 5, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: "other",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyConfirmed",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrPendingTransferConfirmed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "Expired",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ConfirmPendingTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.TransferTxResult{}, db.ErrPendingTransferExpired)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(pending, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				expectConfirmPendingTransferTx(t, store, pending, db.TransferTxResult{}, db.ErrInsufficientFunds)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Eq(pending.ID)).Times(1).Return(db.PendingTransfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MissingPasswordAndCode",
+			body: func(password string, code string) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(t *testing.T, store *mockdb.MockStore, user db.User, pending db.PendingTransfer) {
+				store.EXPECT().GetPendingTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user, password := randomUser(t)
+			secret := enrollRandomTOTP(t, server, &user)
+			user.IsTotpEnabled = true
+
+			pending := db.PendingTransfer{
+				ID:            util.RandomInt(1, 1000),
+				Owner:         user.Username,
+				FromAccountID: 1,
+				ToAccountID:   2,
+				Amount:        5000,
+				ToAmount:      5000,
+				ExchangeRate:  "1",
+				Status:        db.PendingTransferStatusPending,
+				ExpiresAt:     time.Now().Add(time.Minute),
+			}
+			tc.buildStubs(t, store, user, pending)
+
+			code, err := totp.GenerateCode(secret, time.Now())
+			require.NoError(t, err)
+
+			body, err := json.Marshal(tc.body(password, code))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			url := fmt.Sprintf("/pending_transfers/%d/confirm", pending.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+
+			username := user.Username
+			if tc.username != "" {
+				username = tc.username
+			}
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateStepUpTokenAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          func(password string, code string) gin.H
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OKWithPassword",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createStepUpTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.WithinDuration(t, time.Now().Add(time.Minute), rsp.StepUpTokenExpiresAt, time.Second)
+
+				payload, err := tokenMaker.VerifyToken(rsp.StepUpToken)
+				require.NoError(t, err)
+				require.Equal(t, token.TokenTypeStepUp, payload.TokenType)
+				require.NotEqual(t, uuid.Nil, payload.SessionID)
+			},
+		},
+		{
+			name: "OKWithTOTPCode",
+			body: func(password string, code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": "incorrect"}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  false,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			body: func(password string, code string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{FailedAttempts: 5, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "TwoFactorNotEnabled",
+			body: func(password string, code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				user.IsTotpEnabled = false
+
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingPasswordAndCode",
+			body: func(password string, code string) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user, password := randomUser(t)
+			secret := enrollRandomTOTP(t, server, &user)
+			user.IsTotpEnabled = true
+			tc.buildStubs(store, user)
+
+			code, err := totp.GenerateCode(secret, time.Now())
+			require.NoError(t, err)
+
+			body, err := json.Marshal(tc.body(password, code))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/step_up", bytes.NewReader(body))
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hanifsyahsn/simple_bank/token"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (server *Server) renewAccessToken(c *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if refreshPayload.TokenType != token.TokenTypeRefresh {
+		err := errors.New("token is not a refresh token")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	session, err := server.store.GetSession(c.Request.Context(), refreshPayload.SessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		err := errors.New("incorrect session user")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		err := errors.New("mismatched session token")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		err := errors.New("expired session")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// the role of the refresh token was frozen at login, a demoted user must not keep it until the session expires
+	user, err := server.store.GetUser(c.Request.Context(), session.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateSessionToken(user.Username, user.Role, session.ID, token.TokenTypeAccess, server.config.AccessTokenDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiresAt,
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+// getJWKS publishes the public keys that verify our tokens, the set is empty when tokens are signed with a symmetric key
+func (server *Server) getJWKS(c *gin.Context) {
+	jwks := token.JSONWebKeySet{Keys: []token.JSONWebKey{}}
+	if server.keyRing != nil {
+		jwks = server.keyRing.JWKS()
+	}
+
+	c.JSON(http.StatusOK, jwks)
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name          string
+		buildSession  func(refreshToken string, payload *token.Payload) db.Session
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		refreshToken  func(refreshToken string) string
+		tokenType     string
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).Times(1).
+					Return(db.User{Username: username, Role: util.DepositorRole}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.WithinDuration(t, time.Now().Add(time.Minute), rsp.AccessTokenExpiresAt, time.Second)
+			},
+		},
+		{
+			name:      "AccessToken",
+			tokenType: token.TokenTypeAccess,
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			refreshToken: func(refreshToken string) string {
+				return "invalid"
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalErrorOnGetSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InternalErrorOnGetUser",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession(refreshToken, payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.IsBlocked = true
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.Username = "other"
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedSessionToken",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				return randomSession("mismatched", payload)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildSession: func(refreshToken string, payload *token.Payload) db.Session {
+				session := randomSession(refreshToken, payload)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			tokenType := token.TokenTypeRefresh
+			if tc.tokenType != "" {
+				tokenType = tc.tokenType
+			}
+
+			refreshToken, refreshPayload, err := server.tokenMaker.CreateSessionToken(username, util.DepositorRole, uuid.New(), tokenType, time.Hour)
+			require.NoError(t, err)
+
+			session := tc.buildSession(refreshToken, refreshPayload)
+			tc.buildStubs(store, session)
+
+			if tc.refreshToken != nil {
+				refreshToken = tc.refreshToken(refreshToken)
+			}
+
+			body, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRenewAccessTokenAPIRoleChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	// logged in as an admin, demoted to depositor before the access token is renewed
+	user, _ := randomUser(t)
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateSessionToken(user.Username, util.AdminRole, uuid.New(), token.TokenTypeRefresh, time.Hour)
+	require.NoError(t, err)
+	user.Role = util.DepositorRole
+
+	session := randomSession(refreshToken, refreshPayload)
+	store.EXPECT().GetSession(gomock.Any(), gomock.Eq(session.ID)).Times(1).Return(session, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	body, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp renewAccessTokenResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+
+	accessPayload, err := server.tokenMaker.VerifyToken(rsp.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, util.DepositorRole, accessPayload.Role)
+	require.Equal(t, session.ID, accessPayload.SessionID)
+}
+
+func TestJWKSAPI(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	_, err := rand.Read(seed)
+	require.NoError(t, err)
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	privateKeyFile, filePublicKey := writePrivateKey(t)
+
+	testCases := []struct {
+		name          string
+		setupConfig   func(config *util.Config)
+		checkResponse func(t *testing.T, jwks token.JSONWebKeySet)
+	}{
+		{
+			name: "SymmetricKey",
+			setupConfig: func(config *util.Config) {
+			},
+			checkResponse: func(t *testing.T, jwks token.JSONWebKeySet) {
+				require.NotNil(t, jwks.Keys)
+				require.Empty(t, jwks.Keys)
+			},
+		},
+		{
+			name: "KeyRing",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypePasetoPublic
+				config.TokenSigningKeyID = "key-1"
+				config.TokenSigningKey = base64.StdEncoding.EncodeToString(seed)
+			},
+			checkResponse: func(t *testing.T, jwks token.JSONWebKeySet) {
+				require.Len(t, jwks.Keys, 1)
+				require.Equal(t, "key-1", jwks.Keys[0].KeyID)
+				require.Equal(t, base64.RawURLEncoding.EncodeToString(publicKey), jwks.Keys[0].X)
+			},
+		},
+		{
+			name: "JWTPublic",
+			setupConfig: func(config *util.Config) {
+				config.TokenType = token.TypeJWTPublic
+				config.TokenSigningKeyID = "key-1"
+				config.TokenPrivateKeyFile = privateKeyFile
+			},
+			checkResponse: func(t *testing.T, jwks token.JSONWebKeySet) {
+				require.Len(t, jwks.Keys, 1)
+				require.Equal(t, "key-1", jwks.Keys[0].KeyID)
+				require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+				require.Equal(t, "EdDSA", jwks.Keys[0].Alg)
+				require.Equal(t, base64.RawURLEncoding.EncodeToString(filePublicKey), jwks.Keys[0].X)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			config := newTestConfig()
+			tc.setupConfig(&config)
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var jwks token.JSONWebKeySet
+			err = json.Unmarshal(recorder.Body.Bytes(), &jwks)
+			require.NoError(t, err)
+			tc.checkResponse(t, jwks)
+		})
+	}
+}
+
+func randomSession(refreshToken string, payload *token.Payload) db.Session {
+	return db.Session{
+		ID:           payload.SessionID,
+		Username:     payload.Username,
+		RefreshToken: refreshToken,
+		ExpiresAt:    payload.ExpiresAt,
+		CreatedAt:    payload.IssuedAt,
+	}
+}
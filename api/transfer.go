@@ -4,10 +4,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/fx"
 	"github.com/hanifsyahsn/simple_bank/token"
 )
 
@@ -16,6 +20,8 @@ type transferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// QuoteID locks in the rate of a quote from GET /fx/quote when the accounts' currencies differ
+	QuoteID *uuid.UUID `json:"quote_id"`
 }
 
 func (server *Server) createTransfer(c *gin.Context) {
@@ -25,31 +31,106 @@ func (server *Server) createTransfer(c *gin.Context) {
 		return
 	}
 
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	idempotency, err := idempotencyParams(c, authPayload.Username, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// a transfer above the step-up threshold is answered with the pending transfer waiting for confirmation
+	stepUp := server.requiresStepUp(req.Currency, req.Amount)
+	status := http.StatusCreated
+	if stepUp {
+		status = http.StatusAccepted
+	}
+	if idempotency != nil && server.replayIdempotentRequest(c, idempotency, status) {
+		return
+	}
+
 	fromAccount, valid := server.validAccount(c, req.FromAccountID, req.Currency)
 	if !valid {
 		return
 	}
 
-	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
 	if authPayload.Username != fromAccount.Owner {
 		err := errors.New("you are not the owner of this account")
 		c.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
-	_, valid = server.validAccount(c, req.ToAccountID, req.Currency)
+	toAccount, valid := server.loadAccount(c, req.ToAccountID)
 	if !valid {
 		return
 	}
+	if toAccount.Owner == db.SystemUsername {
+		err := errors.New("cannot transfer to a settlement account, use a withdrawal instead")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
 
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
 		Amount:        req.Amount,
+		Idempotency:   idempotency,
+	}
+	stepUpExpiresAt := time.Now().Add(server.config.StepUpDuration)
+
+	if req.QuoteID != nil || toAccount.Currency != fromAccount.Currency {
+		var rate *big.Rat
+		if req.QuoteID != nil {
+			var quote db.FxQuote
+			quote, rate, valid = server.validQuote(c, *req.QuoteID, fromAccount.Currency, toAccount.Currency)
+			if !valid {
+				return
+			}
+			arg.QuoteID = uuid.NullUUID{UUID: quote.ID, Valid: true}
+			// the rate of the quote cannot be locked in past its expiry
+			if quote.ExpiresAt.Before(stepUpExpiresAt) {
+				stepUpExpiresAt = quote.ExpiresAt
+			}
+		} else {
+			rate, valid = server.exchangeRate(c, fromAccount.Currency, toAccount.Currency)
+			if !valid {
+				return
+			}
+		}
+
+		arg.ToAmount, err = fx.Convert(req.Amount, rate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		arg.ExchangeRate = fx.FormatRate(rate)
+		if arg.ToAmount <= 0 {
+			err := fmt.Errorf("amount %d %s is too small to convert to %s", req.Amount, fromAccount.Currency, toAccount.Currency)
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+	}
+
+	if stepUp {
+		server.createPendingTransfer(c, authPayload.Username, arg, stepUpExpiresAt)
+		return
 	}
 
 	result, err := server.store.TransferTx(c.Request.Context(), arg)
 	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+			return
+		}
+		if errors.Is(err, db.ErrQuoteAlreadyUsed) {
+			c.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrAccountFrozen) {
+			c.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -58,19 +139,200 @@ func (server *Server) createTransfer(c *gin.Context) {
 }
 
 func (server *Server) validAccount(c *gin.Context, accountID int64, currency string) (db.Account, bool) {
-	account, err := server.store.GetAccount(c, accountID)
+	account, valid := server.loadAccount(c, accountID)
+	if !valid {
+		return account, false
+	}
+	if !server.matchingCurrency(c, account, currency) {
+		return account, false
+	}
+	return account, true
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getTransfer(c *gin.Context) {
+	var req getTransferRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(c.Request.Context(), req.ID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			c.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
+			return
 		}
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
-		return account, false
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	if canReadAnyAccount(authPayload.Role) {
+		c.JSON(http.StatusOK, transfer)
+		return
 	}
-	if account.Currency != currency {
-		err := fmt.Errorf("invalid currency %s, account currency %s", currency, account.Currency)
+
+	for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+		account, err := server.store.GetAccount(c.Request.Context(), accountID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if account.Owner == authPayload.Username {
+			c.JSON(http.StatusOK, transfer)
+			return
+		}
+	}
+
+	err = errors.New("transfer does not belong to authenticated user")
+	c.JSON(http.StatusUnauthorized, errorResponse(err))
+}
+
+type reverseTransferRequest struct {
+	// Amount is the part of the transfer to refund, in the destination currency, the whole remainder is refunded when it is omitted
+	Amount int64  `json:"amount" binding:"omitempty,gt=0"`
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+func (server *Server) reverseTransfer(c *gin.Context) {
+	var uri getTransferRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
 		c.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+		return
 	}
-	return account, true
+
+	var req reverseTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	idempotency, err := idempotencyParams(c, authPayload.Username, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if idempotency != nil && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(c.Request.Context(), uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// only the recipient can give the money back
+	if _, valid := server.ownedAccount(c, transfer.ToAccountID); !valid {
+		return
+	}
+
+	result, err := server.store.ReverseTransferTx(c.Request.Context(), db.ReverseTransferTxParams{
+		TransferID:  transfer.ID,
+		Amount:      req.Amount,
+		Reason:      req.Reason,
+		Actor:       authPayload.Username,
+		Idempotency: idempotency,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) && server.replayIdempotentRequest(c, idempotency, http.StatusCreated) {
+			return
+		}
+		switch {
+		case errors.Is(err, db.ErrTransferAlreadyReversed), errors.Is(err, db.ErrReversalNotReversible):
+			c.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, db.ErrSettlementNotReversible):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, db.ErrRefundExceedsTransfer), errors.Is(err, db.ErrRefundTooSmall), errors.Is(err, db.ErrInsufficientFunds):
+			c.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		case errors.Is(err, db.ErrAccountFrozen):
+			c.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+const (
+	transferDirectionIn   = "in"
+	transferDirectionOut  = "out"
+	transferDirectionBoth = "both"
+)
+
+type listAccountTransfersRequest struct {
+	PageSize  int32      `form:"page_size,default=10" binding:"min=1,max=10"`
+	PageID    int32      `form:"page_id,default=1" binding:"min=1"`
+	Direction string     `form:"direction,default=both" binding:"oneof=in out both"`
+	MinAmount *int64     `form:"min_amount" binding:"omitempty,min=0"`
+	MaxAmount *int64     `form:"max_amount" binding:"omitempty,min=0"`
+	StartTime *time.Time `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   *time.Time `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+func (server *Server) listAccountTransfers(c *gin.Context) {
+	var uri getAccountRequest
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAccountTransfersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.MinAmount != nil && req.MaxAmount != nil && *req.MinAmount > *req.MaxAmount {
+		err := errors.New("min_amount must not be greater than max_amount")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.StartTime != nil && req.EndTime != nil && !req.StartTime.Before(*req.EndTime) {
+		err := errors.New("start_time must be before end_time")
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, valid := server.readableAccount(c, uri.ID)
+	if !valid {
+		return
+	}
+
+	arg := db.ListTransfersParams{
+		Direction: req.Direction,
+		AccountID: account.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	}
+	if req.MinAmount != nil {
+		arg.MinAmount = sql.NullInt64{Int64: *req.MinAmount, Valid: true}
+	}
+	if req.MaxAmount != nil {
+		arg.MaxAmount = sql.NullInt64{Int64: *req.MaxAmount, Valid: true}
+	}
+	if req.StartTime != nil {
+		arg.StartTime = sql.NullTime{Time: *req.StartTime, Valid: true}
+	}
+	if req.EndTime != nil {
+		arg.EndTime = sql.NullTime{Time: *req.EndTime, Valid: true}
+	}
+
+	transfers, err := server.store.ListTransfers(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, transfers)
 }
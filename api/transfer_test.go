@@ -2,16 +2,19 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
 	"github.com/hanifsyahsn/simple_bank/token"
@@ -44,8 +47,32 @@ func TestTransferAPI(t *testing.T) {
 		Currency: util.CAD,
 		Balance:  100,
 	}
+	toAccountIDR := db.Account{
+		ID:       5,
+		Owner:    "E",
+		Currency: "IDR",
+		Balance:  100,
+	}
+	settlementAccount := db.Account{
+		ID:       6,
+		Owner:    db.SystemUsername,
+		Currency: util.USD,
+		Balance:  -100,
+	}
 	transferAmount := util.RandomInt(1, 50)
 
+	quote := db.FxQuote{
+		ID:           uuid.New(),
+		Username:     fromAccount.Owner,
+		FromCurrency: util.USD,
+		ToCurrency:   util.CAD,
+		Rate:         "1.5",
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}
+	expiredQuote := quote
+	expiredQuote.ID = uuid.New()
+	expiredQuote.ExpiresAt = time.Now().Add(-time.Second)
+
 	fromAccountEntry := createEntry(fromAccount.ID, transferAmount)
 	toAccountEntry := createEntry(toAccount.ID, -transferAmount)
 
@@ -68,7 +95,7 @@ func TestTransferAPI(t *testing.T) {
 				Amount:        transferAmount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccount.ID,
@@ -102,7 +129,7 @@ func TestTransferAPI(t *testing.T) {
 				Amount:        transferAmount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccount.ID,
@@ -126,7 +153,7 @@ func TestTransferAPI(t *testing.T) {
 			name: "InvalidCurrencyFromAccount",
 			arg:  db.TransferTxParams{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccountCAD.ID,
@@ -147,29 +174,217 @@ func TestTransferAPI(t *testing.T) {
 			},
 		},
 		{
-			name: "InvalidCurrencyToAccount",
+			name: "CrossCurrency",
+			arg: db.TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        transferAmount,
+				ToAmount:      transferAmount * 137 / 100,
+				ExchangeRate:  "1.37",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{
+				Transfer:    createTransfer(fromAccount.ID, toAccountCAD.ID, transferAmount),
+				ToAccount:   toAccountCAD,
+				FromAccount: fromAccount,
+			},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountCAD.ID)).Times(1).Return(toAccountCAD, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(expRes, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				requireBodyMatchTransfer(t, recorder.Body, expRes)
+			},
+		},
+		{
+			name: "CrossCurrencyWithQuote",
+			arg: db.TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        transferAmount,
+				ToAmount:      transferAmount * 3 / 2,
+				ExchangeRate:  "1.5",
+				QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+				QuoteID:       &quote.ID,
+			},
+			expectResp: db.TransferTxResult{
+				Transfer:    createTransfer(fromAccount.ID, toAccountCAD.ID, transferAmount),
+				ToAccount:   toAccountCAD,
+				FromAccount: fromAccount,
+			},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountCAD.ID)).Times(1).Return(toAccountCAD, nil)
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(quote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(expRes, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				requireBodyMatchTransfer(t, recorder.Body, expRes)
+			},
+		},
+		{
+			name: "QuoteAlreadyUsed",
+			arg: db.TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        transferAmount,
+				ToAmount:      transferAmount * 3 / 2,
+				ExchangeRate:  "1.5",
+				QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+				QuoteID:       &quote.ID,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountCAD.ID)).Times(1).Return(toAccountCAD, nil)
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(quote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, db.ErrQuoteAlreadyUsed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "QuoteExpired",
 			arg:  db.TransferTxParams{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccount.ID,
 				ToAccountID:   toAccountCAD.ID,
 				Amount:        transferAmount,
 				Currency:      util.USD,
+				QuoteID:       &expiredQuote.ID,
 			},
 			expectResp: db.TransferTxResult{},
 			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
 				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountCAD.ID)).Times(1).Return(toAccountCAD, nil)
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(expiredQuote.ID)).Times(1).Return(expiredQuote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "QuoteCurrencyMismatch",
+			arg:  db.TransferTxParams{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+				QuoteID:       &quote.ID,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(quote, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "RateNotFound",
+			arg:  db.TransferTxParams{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountIDR.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountIDR.ID)).Times(1).Return(toAccountIDR, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "ConvertedAmountOutOfRange",
+			arg:  db.TransferTxParams{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        math.MaxInt64,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountCAD.ID)).Times(1).Return(toAccountCAD, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ToSettlementAccount",
+			arg:  db.TransferTxParams{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   settlementAccount.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(settlementAccount.ID)).Times(1).Return(settlementAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
 				require.Equal(t, http.StatusBadRequest, recorder.Code)
-
-				var resp map[string]string
-				err := json.Unmarshal(recorder.Body.Bytes(), &resp)
-				require.NoError(t, err)
-				require.Contains(t, resp["error"], "invalid currency")
 			},
 		},
 		{
@@ -180,7 +395,7 @@ func TestTransferAPI(t *testing.T) {
 				Amount:        transferAmount,
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccount.ID,
@@ -198,11 +413,150 @@ func TestTransferAPI(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, recorder.Code)
 			},
 		},
+		{
+			name: "InsufficientFunds",
+			arg: db.TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        transferAmount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "AccountFrozen",
+			arg: db.TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        transferAmount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        transferAmount,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.TransferTxResult{}, db.ErrAccountFrozen)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "StepUpRequired",
+			arg: db.TransferTxParams{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        5000,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccount.ID,
+				Amount:        5000,
+				Currency:      util.USD,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreatePendingTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePendingTransferTxParams) (db.PendingTransfer, error) {
+						require.Equal(t, fromAccount.Owner, arg.Owner)
+						require.Equal(t, int64(5000), arg.Amount)
+						require.Equal(t, int64(5000), arg.ToAmount)
+						require.Equal(t, "1", arg.ExchangeRate)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return db.PendingTransfer{
+							ID:            1,
+							Owner:         arg.Owner,
+							FromAccountID: arg.FromAccountID,
+							ToAccountID:   arg.ToAccountID,
+							Amount:        arg.Amount,
+							ToAmount:      arg.ToAmount,
+							ExchangeRate:  arg.ExchangeRate,
+							Status:        db.PendingTransferStatusPending,
+							ExpiresAt:     arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				var pending db.PendingTransfer
+				err := json.Unmarshal(recorder.Body.Bytes(), &pending)
+				require.NoError(t, err)
+				require.Equal(t, db.PendingTransferStatusPending, pending.Status)
+				require.Equal(t, int64(5000), pending.Amount)
+			},
+		},
+		{
+			name: "StepUpExpiresWithQuote",
+			arg:  db.TransferTxParams{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			reqBody: transferRequest{
+				FromAccountID: fromAccount.ID,
+				ToAccountID:   toAccountCAD.ID,
+				Amount:        5000,
+				Currency:      util.USD,
+				QuoteID:       &quote.ID,
+			},
+			expectResp: db.TransferTxResult{},
+			buildStub: func(store *mockdb.MockStore, arg db.TransferTxParams, expRes db.TransferTxResult) {
+				shortQuote := quote
+				shortQuote.ExpiresAt = time.Now().Add(10 * time.Second)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccountCAD.ID)).Times(1).Return(toAccountCAD, nil)
+				store.EXPECT().GetFxQuote(gomock.Any(), gomock.Eq(quote.ID)).Times(1).Return(shortQuote, nil)
+
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreatePendingTransferTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreatePendingTransferTxParams) (db.PendingTransfer, error) {
+						require.Equal(t, int64(7500), arg.ToAmount)
+						require.Equal(t, uuid.NullUUID{UUID: quote.ID, Valid: true}, arg.QuoteID)
+						require.Equal(t, shortQuote.ExpiresAt, arg.ExpiresAt)
+						return db.PendingTransfer{ID: 1, Status: db.PendingTransferStatusPending}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, expRes db.TransferTxResult) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
 		{
 			name: "AccountNotFound",
 			arg:  db.TransferTxParams{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccount.ID,
@@ -222,7 +576,7 @@ func TestTransferAPI(t *testing.T) {
 			name: "InternalErrorOnGetAccount",
 			arg:  db.TransferTxParams{},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
 			},
 			reqBody: transferRequest{
 				FromAccountID: fromAccount.ID,
@@ -309,3 +663,765 @@ func requireBodyMatchTransfer(t *testing.T, buffer *bytes.Buffer, expected db.Tr
 	require.Equal(t, expected.FromEntry, transferResult.FromEntry)
 	require.Equal(t, expected.ToEntry, transferResult.ToEntry)
 }
+
+func TestTransferAPIIdempotency(t *testing.T) {
+	fromAccount := db.Account{
+		ID:       1,
+		Owner:    "A",
+		Currency: util.USD,
+		Balance:  100,
+	}
+	toAccount := db.Account{
+		ID:       2,
+		Owner:    "B",
+		Currency: util.USD,
+		Balance:  100,
+	}
+	transferAmount := util.RandomInt(1, 50)
+	idempotencyKey := util.RandomString(16)
+
+	reqBody := transferRequest{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        transferAmount,
+		Currency:      util.USD,
+	}
+	requestHash, err := hashIdempotentRequest(http.MethodPost, "/transfers", reqBody)
+	require.NoError(t, err)
+
+	expectResp := db.TransferTxResult{
+		Transfer:    createTransfer(fromAccount.ID, toAccount.ID, transferAmount),
+		FromAccount: fromAccount,
+		ToAccount:   toAccount,
+		FromEntry:   createEntry(fromAccount.ID, -transferAmount),
+		ToEntry:     createEntry(toAccount.ID, transferAmount),
+	}
+	storedBody, err := json.Marshal(expectResp)
+	require.NoError(t, err)
+
+	storedKey := db.IdempotencyKey{
+		Username:     fromAccount.Owner,
+		Key:          idempotencyKey,
+		RequestHash:  requestHash,
+		ResponseBody: storedBody,
+	}
+	getKeyArg := db.GetIdempotencyKeyParams{
+		Username: fromAccount.Owner,
+		Key:      idempotencyKey,
+	}
+	transferArg := db.TransferTxParams{
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        transferAmount,
+		Idempotency: &db.IdempotencyParams{
+			Username:    fromAccount.Owner,
+			Key:         idempotencyKey,
+			RequestHash: requestHash,
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		idempotencyKey string
+		buildStub      func(store *mockdb.MockStore)
+		checkResponse  func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "FirstRequest",
+			idempotencyKey: idempotencyKey,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(transferArg)).Times(1).Return(expectResp, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Empty(t, recorder.Header().Get(idempotentReplayedHeader))
+				requireBodyMatchTransfer(t, recorder.Body, expectResp)
+			},
+		},
+		{
+			name:           "Replay",
+			idempotencyKey: idempotencyKey,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(storedKey, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+				requireBodyMatchTransfer(t, recorder.Body, expectResp)
+			},
+		},
+		{
+			name:           "ReusedKeyWithDifferentRequest",
+			idempotencyKey: idempotencyKey,
+			buildStub: func(store *mockdb.MockStore) {
+				conflictingKey := storedKey
+				conflictingKey.RequestHash = "other"
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(conflictingKey, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:           "ConcurrentRequestWithSameKey",
+			idempotencyKey: idempotencyKey,
+			buildStub: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, sql.ErrNoRows),
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(storedKey, nil),
+				)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Eq(transferArg)).Times(1).Return(db.TransferTxResult{}, db.ErrIdempotencyKeyConflict)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+				requireBodyMatchTransfer(t, recorder.Body, expectResp)
+			},
+		},
+		{
+			name:           "InternalErrorOnGetIdempotencyKey",
+			idempotencyKey: idempotencyKey,
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, sql.ErrConnDone)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:           "KeyTooLong",
+			idempotencyKey: util.RandomString(maxIdempotencyKeyLength + 1),
+			buildStub: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			testCase.buildStub(store)
+
+			server := newTestServer(t, store)
+
+			body, err := json.Marshal(&reqBody)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			request.Header.Set(idempotencyKeyHeader, testCase.idempotencyKey)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	fromAccount := randomAccount(user.Username)
+	toAccount := randomAccount(util.RandomOwner())
+	transfer := randomTransfer(fromAccount.ID, toAccount.ID)
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OKSender",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransferRow(t, recorder.Body, transfer)
+			},
+		},
+		{
+			name:       "OKRecipient",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, toAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransferRow(t, recorder.Body, transfer)
+			},
+		},
+		{
+			name:       "OKAuditor",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "auditor", util.AuditorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransferRow(t, recorder.Body, transfer)
+			},
+		},
+		{
+			name:       "Unauthorized",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "NoAuthorization",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "InternalServerError",
+			transferID: transfer.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/transfers/%d", tc.transferID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReverseTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	fromAccount := randomAccount(util.RandomOwner())
+	toAccount := randomAccount(user.Username)
+	transfer := randomTransfer(fromAccount.ID, toAccount.ID)
+	reason := util.RandomString(12)
+
+	result := db.ReverseTransferTxResult{
+		OriginalTransfer: transfer,
+		Reversal: db.TransferTxResult{
+			Transfer:    randomTransfer(toAccount.ID, fromAccount.ID),
+			FromAccount: toAccount,
+			ToAccount:   fromAccount,
+		},
+	}
+	result.OriginalTransfer.RefundedAmount = transfer.ToAmount
+	result.Reversal.Transfer.ReversalOf = sql.NullInt64{Int64: transfer.ID, Valid: true}
+
+	testCases := []struct {
+		name          string
+		reqBody       reverseTransferRequest
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "OK",
+			reqBody: reverseTransferRequest{Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Reason:     reason,
+					Actor:      user.Username,
+				}
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+
+				data, err := io.ReadAll(recorder.Body)
+				require.NoError(t, err)
+
+				var gotResult db.ReverseTransferTxResult
+				err = json.Unmarshal(data, &gotResult)
+				require.NoError(t, err)
+				require.Equal(t, result.OriginalTransfer.RefundedAmount, gotResult.OriginalTransfer.RefundedAmount)
+				require.Equal(t, result.Reversal.Transfer.ReversalOf, gotResult.Reversal.Transfer.ReversalOf)
+			},
+		},
+		{
+			name:    "PartialRefund",
+			reqBody: reverseTransferRequest{Amount: 1, Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ReverseTransferTxParams{
+					TransferID: transfer.ID,
+					Amount:     1,
+					Reason:     reason,
+					Actor:      user.Username,
+				}
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name:    "SenderCannotReverse",
+			reqBody: reverseTransferRequest{Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, fromAccount.Owner, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:    "MissingReason",
+			reqBody: reverseTransferRequest{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:    "TransferNotFound",
+			reqBody: reverseTransferRequest{Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(db.Transfer{}, sql.ErrNoRows)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:    "AlreadyReversed",
+			reqBody: reverseTransferRequest{Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, db.ErrTransferAlreadyReversed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:    "DepositNotReversible",
+			reqBody: reverseTransferRequest{Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				deposit := randomTransfer(randomAccount(db.SystemUsername).ID, toAccount.ID)
+				deposit.ID = transfer.ID
+
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(deposit, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, db.ErrSettlementNotReversible)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:    "RefundExceedsTransfer",
+			reqBody: reverseTransferRequest{Amount: transfer.ToAmount + 1, Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, db.ErrRefundExceedsTransfer)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name:    "InternalServerError",
+			reqBody: reverseTransferRequest{Reason: reason},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ReverseTransferTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.reqBody)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/transfers/%d/reverse", transfer.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListAccountTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	other := randomAccount(util.RandomOwner())
+
+	n := 5
+	transfers := make([]db.Transfer, n)
+	for i := range transfers {
+		transfers[i] = randomTransfer(account.ID, other.ID)
+	}
+
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.AddDate(0, 1, 0)
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListTransfersParams{
+					Direction: transferDirectionBoth,
+					AccountID: account.ID,
+					Limit:     5,
+					Offset:    0,
+				}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Eq(arg)).Times(1).Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransfers(t, recorder.Body, transfers)
+			},
+		},
+		{
+			name:      "OKWithFilters",
+			accountID: account.ID,
+			query: fmt.Sprintf("page_id=2&page_size=5&direction=out&min_amount=10&max_amount=100&start_time=%s&end_time=%s",
+				startTime.Format(time.RFC3339), endTime.Format(time.RFC3339)),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ListTransfersParams{
+					Direction: transferDirectionOut,
+					AccountID: account.ID,
+					MinAmount: sql.NullInt64{Int64: 10, Valid: true},
+					MaxAmount: sql.NullInt64{Int64: 100, Valid: true},
+					StartTime: sql.NullTime{Time: startTime, Valid: true},
+					EndTime:   sql.NullTime{Time: endTime, Valid: true},
+					Limit:     5,
+					Offset:    5,
+				}
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Eq(arg)).Times(1).Return(transfers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchTransfers(t, recorder.Body, transfers)
+			},
+		},
+		{
+			name:      "Unauthorized",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "unauthorized_user", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "AccountNotFound",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidDirection",
+			accountID: account.ID,
+			query:     "direction=sideways",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidAmountRange",
+			accountID: account.ID,
+			query:     "min_amount=100&max_amount=10",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidTimeRange",
+			accountID: account.ID,
+			query: fmt.Sprintf("start_time=%s&end_time=%s",
+				endTime.Format(time.RFC3339), startTime.Format(time.RFC3339)),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidPageSize",
+			accountID: account.ID,
+			query:     "page_size=100",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalServerError",
+			accountID: account.ID,
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().ListTransfers(gomock.Any(), gomock.Any()).Times(1).Return([]db.Transfer{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/transfers?%s", tc.accountID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomTransfer(fromAccountID, toAccountID int64) db.Transfer {
+	return db.Transfer{
+		ID:            util.RandomInt(1, 1000),
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        util.RandomMoney(),
+	}
+}
+
+func requireBodyMatchTransferRow(t *testing.T, buffer *bytes.Buffer, expected db.Transfer) {
+	data, err := io.ReadAll(buffer)
+	require.NoError(t, err)
+
+	var gotTransfer db.Transfer
+	err = json.Unmarshal(data, &gotTransfer)
+	require.NoError(t, err)
+	require.Equal(t, expected, gotTransfer)
+}
+
+func requireBodyMatchTransfers(t *testing.T, buffer *bytes.Buffer, expected []db.Transfer) {
+	data, err := io.ReadAll(buffer)
+	require.NoError(t, err)
+
+	var gotTransfers []db.Transfer
+	err = json.Unmarshal(data, &gotTransfers)
+	require.NoError(t, err)
+	require.Equal(t, expected, gotTransfers)
+}
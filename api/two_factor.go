@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+// recoveryCodeCount is how many recovery codes are handed out on enrollment
+const recoveryCodeCount = 10
+
+// maxLoginChallengeAttempts is how many wrong codes a login challenge accepts before it is discarded
+const maxLoginChallengeAttempts = 5
+
+type enrollTOTPResponse struct {
+	OtpauthURI    string   `json:"otpauth_uri"`
+	Secret        string   `json:"secret"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (server *Server) enrollTOTP(c *gin.Context) {
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(c.Request.Context(), authPayload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsTotpEnabled {
+		err := errors.New("two factor authentication is already enabled")
+		c.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	secret, uri, err := util.GenerateTOTP(server.config.TwoFactorIssuer, user.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	encryptedSecret, err := util.EncryptSecret(server.config.TwoFactorEncryptionKey, []byte(secret), []byte(user.Username))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	recoveryCodes, err := util.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	recoveryCodeHashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		recoveryCodeHashes[i] = util.HashRecoveryCode(code)
+	}
+
+	arg := db.EnrollTOTPTxParams{
+		Username:           user.Username,
+		TotpSecret:         encryptedSecret,
+		RecoveryCodeHashes: recoveryCodeHashes,
+	}
+
+	_, err = server.store.EnrollTOTPTx(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := enrollTOTPResponse{
+		OtpauthURI:    uri,
+		Secret:        secret,
+		RecoveryCodes: recoveryCodes,
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+type verifyTOTPRequest struct {
+	Code string `json:"code" binding:"required,numeric,len=6"`
+}
+
+func (server *Server) verifyTOTP(c *gin.Context) {
+	var req verifyTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(c.Request.Context(), authPayload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsTotpEnabled {
+		err := errors.New("two factor authentication is already enabled")
+		c.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	if user.TotpSecret == nil {
+		err := errors.New("two factor authentication is not enrolled")
+		c.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	valid, err := server.validTOTPCode(c.Request.Context(), user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		err := errors.New("invalid two factor code")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	user, err = server.store.EnableUserTOTP(c.Request.Context(), user.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type loginChallengeResponse struct {
+	TwoFactorRequired  bool      `json:"two_factor_required"`
+	ChallengeToken     uuid.UUID `json:"challenge_token"`
+	ChallengeExpiresAt time.Time `json:"challenge_expires_at"`
+}
+
+// startLoginChallenge answers a correct password of a user with 2FA enabled, the tokens are only
+// issued once the challenge is completed with a code
+func (server *Server) startLoginChallenge(c *gin.Context, user db.User) {
+	arg := db.CreateLoginChallengeParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(server.config.TwoFactorChallengeDuration),
+	}
+
+	challenge, err := server.store.CreateLoginChallenge(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := loginChallengeResponse{
+		TwoFactorRequired:  true,
+		ChallengeToken:     challenge.ID,
+		ChallengeExpiresAt: challenge.ExpiresAt,
+	}
+	c.JSON(http.StatusOK, rsp)
+}
+
+type loginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required,uuid"`
+	// Code is either the current TOTP code or one of the recovery codes
+	Code string `json:"code" binding:"required"`
+}
+
+func (server *Server) loginTwoFactor(c *gin.Context) {
+	var req loginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challenge, err := server.store.GetLoginChallenge(c.Request.Context(), uuid.MustParse(req.ChallengeToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err := errors.New("invalid login challenge")
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(challenge.ExpiresAt) {
+		err := errors.New("expired login challenge")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if challenge.FailedAttempts >= maxLoginChallengeAttempts {
+		err := errors.New("too many failed attempts")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// wrong codes count towards the same lockout as wrong passwords, otherwise a known password would allow
+	// guessing codes with a new challenge every few attempts
+	retryAfter, err := server.loginRetryAfter(c.Request.Context(), challenge.Username, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		abortTooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	user, err := server.store.GetUser(c.Request.Context(), challenge.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	valid, err := server.validTOTPCode(c.Request.Context(), user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		valid, err = server.useRecoveryCode(c, user, req.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err = server.recordLoginAttempt(c, user.Username, valid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		_, err = server.store.AddLoginChallengeFailedAttempt(c.Request.Context(), challenge.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		err := errors.New("invalid two factor code")
+		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	err = server.store.DeleteLoginChallenge(c.Request.Context(), challenge.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.startSession(c, user)
+}
+
+// validTOTPCode decrypts the TOTP secret of the user and checks the code against it. An accepted code uses up
+// its time step, so a code that has already been accepted once is not valid again
+func (server *Server) validTOTPCode(ctx context.Context, user db.User, code string) (bool, error) {
+	if user.TotpSecret == nil {
+		return false, nil
+	}
+
+	secret, err := util.DecryptSecret(server.config.TwoFactorEncryptionKey, user.TotpSecret, []byte(user.Username))
+	if err != nil {
+		return false, err
+	}
+
+	step, valid := util.ValidateTOTP(code, string(secret), time.Now())
+	if !valid {
+		return false, nil
+	}
+
+	_, err = server.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+		Step:     step,
+		Username: user.Username,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// useRecoveryCode marks the recovery code as used, a code that is unknown or already used is not valid
+func (server *Server) useRecoveryCode(c *gin.Context, user db.User, code string) (bool, error) {
+	_, err := server.store.UseRecoveryCode(c.Request.Context(), db.UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.HashRecoveryCode(code),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,479 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+// enrollRandomTOTP gives the user a TOTP secret encrypted with the key of the server
+func enrollRandomTOTP(t *testing.T, server *Server, user *db.User) string {
+	secret, _, err := util.GenerateTOTP(server.config.TwoFactorIssuer, user.Username)
+	require.NoError(t, err)
+
+	user.TotpSecret, err = util.EncryptSecret(server.config.TwoFactorEncryptionKey, []byte(secret), []byte(user.Username))
+	require.NoError(t, err)
+	return secret
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	enabledUser := user
+	enabledUser.IsTotpEnabled = true
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().EnrollTOTPTx(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.EnrollTOTPTxParams) (db.User, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.NotEmpty(t, arg.TotpSecret)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+						return user, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Contains(t, rsp.OtpauthURI, "otpauth://totp/")
+				require.Contains(t, rsp.OtpauthURI, rsp.Secret)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabledUser, nil)
+				store.EXPECT().EnrollTOTPTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().EnrollTOTPTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/2fa/enroll", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestVerifyTOTPAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          func(code string) gin.H
+		buildStubs    func(store *mockdb.MockStore, user db.User)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				enabledUser := user
+				enabledUser.IsTotpEnabled = true
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().EnableUserTOTP(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(enabledUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.TwoFactorEnabled)
+			},
+		},
+		{
+			name: "ReusedCode",
+			body: func(code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().EnableUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: func(code string) gin.H {
+				return gin.H{"code": "000000"}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().EnableUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			body: func(code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				user.TotpSecret = nil
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().EnableUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			body: func(code string) gin.H {
+				return gin.H{"code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				user.IsTotpEnabled = true
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().EnableUserTOTP(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidRequest",
+			body: func(code string) gin.H {
+				return gin.H{"code": "abc"}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user, _ := randomUser(t)
+			secret := enrollRandomTOTP(t, server, &user)
+			tc.buildStubs(store, user)
+
+			code, err := totp.GenerateCode(secret, time.Now())
+			require.NoError(t, err)
+
+			body, err := json.Marshal(tc.body(code))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/2fa/verify", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLoginTwoFactorAPI(t *testing.T) {
+	recoveryCode := "abcde-fghij"
+
+	testCases := []struct {
+		name          string
+		body          func(challenge db.LoginChallenge, code string) gin.H
+		buildStubs    func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKWithTOTPCode",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().DeleteLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{ID: uuid.New()}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+			},
+		},
+		{
+			name: "OKWithRecoveryCode",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": recoveryCode}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				arg := db.UseRecoveryCodeParams{
+					Username: user.Username,
+					CodeHash: util.HashRecoveryCode(recoveryCode),
+				}
+
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Eq(arg)).Times(1).Return(db.RecoveryCode{}, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().DeleteLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{ID: uuid.New()}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": "000000"}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  false,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().AddLoginChallengeFailedAttempt(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().DeleteLoginChallenge(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReusedTOTPCode",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(1).Return(db.RecoveryCode{}, sql.ErrNoRows)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().AddLoginChallengeFailedAttempt(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{FailedAttempts: 5, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "ExpiredChallenge",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				challenge.ExpiresAt = time.Now().Add(-time.Minute)
+
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "TooManyFailedAttempts",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				challenge.FailedAttempts = maxLoginChallengeAttempts
+
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(challenge, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ChallengeNotFound",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": challenge.ID, "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Eq(challenge.ID)).Times(1).Return(db.LoginChallenge{}, sql.ErrNoRows)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidChallengeToken",
+			body: func(challenge db.LoginChallenge, code string) gin.H {
+				return gin.H{"challenge_token": "invalid", "code": code}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, challenge db.LoginChallenge) {
+				store.EXPECT().GetLoginChallenge(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			user, _ := randomUser(t)
+			secret := enrollRandomTOTP(t, server, &user)
+			user.IsTotpEnabled = true
+
+			challenge := db.LoginChallenge{
+				ID:        uuid.New(),
+				Username:  user.Username,
+				ExpiresAt: time.Now().Add(time.Minute),
+			}
+			tc.buildStubs(store, user, challenge)
+
+			code, err := totp.GenerateCode(secret, time.Now())
+			require.NoError(t, err)
+
+			body, err := json.Marshal(tc.body(challenge, code))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/login/2fa", bytes.NewReader(body))
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -1,21 +1,26 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"io"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/token"
 	"github.com/hanifsyahsn/simple_bank/util"
 	"github.com/lib/pq"
 )
 
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -24,6 +29,8 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	EmailVerified     bool      `json:"email_verified"`
+	TwoFactorEnabled  bool      `json:"two_factor_enabled"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -33,6 +40,8 @@ func newUserResponse(u db.User) userResponse {
 		Username:          u.Username,
 		FullName:          u.FullName,
 		Email:             u.Email,
+		EmailVerified:     u.IsEmailVerified,
+		TwoFactorEnabled:  u.IsTotpEnabled,
 		PasswordChangedAt: u.PasswordChangedAt,
 		CreatedAt:         u.CreatedAt,
 	}
@@ -51,20 +60,34 @@ func (server *Server) createUser(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	if !server.checkPasswordPolicy(c, "Password", req.Password, db.User{Username: req.Username, Email: req.Email}) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		FullName:       req.FullName,
-		Email:          req.Email,
-		HashedPassword: hashedPassword,
+	secretCode, err := util.GenerateSecureToken(verifyEmailCodeSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	user, err := server.store.CreateUser(c.Request.Context(), arg)
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			FullName:       req.FullName,
+			Email:          req.Email,
+			HashedPassword: hashedPassword,
+		},
+		SecretCodeHash: util.HashSecureToken(secretCode),
+		ExpiredAt:      time.Now().Add(server.config.VerifyEmailDuration),
+	}
+
+	result, err := server.store.CreateUserTx(c.Request.Context(), arg)
 	if err != nil {
 		var e *pq.Error
 		if errors.As(err, &e) {
@@ -78,7 +101,14 @@ func (server *Server) createUser(c *gin.Context) {
 		return
 	}
 
-	rsp := newUserResponse(user)
+	// the email is only sent once the user has been committed, a link that cannot be sent does not undo
+	// the sign up since the user can ask for a new one
+	err = server.sendVerifyEmail(c.Request.Context(), result.User, result.VerifyEmail, secretCode)
+	if err != nil {
+		log.Printf("cannot send verification email to user %s: %v", result.User.Username, err)
+	}
+
+	rsp := newUserResponse(result.User)
 
 	c.JSON(http.StatusCreated, rsp)
 }
@@ -89,8 +119,12 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(c *gin.Context) {
@@ -106,32 +140,199 @@ func (server *Server) loginUser(c *gin.Context) {
 		return
 	}
 
-	user, err := server.store.GetUser(c.Request.Context(), req.Username)
+	retryAfter, err := server.loginRetryAfter(c.Request.Context(), req.Username, c.ClientIP())
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			c.JSON(http.StatusNotFound, errorResponse(err))
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if retryAfter > 0 {
+		abortTooManyLoginAttempts(c, retryAfter)
+		return
+	}
+
+	user, err := server.store.GetUser(c.Request.Context(), req.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var validPassword bool
+	if err == nil {
+		validPassword = server.passwordHasher.CheckPasswordHash(req.Password, user.HashedPassword) == nil
+	} else {
+		server.checkDummyPassword(req.Password)
+	}
+
+	// the password of a user with 2FA enabled is only half of a successful login, the attempt is recorded once
+	// the code has been checked so that a correct password does not clear the failed codes
+	if !validPassword || !user.IsTotpEnabled {
+		err = server.recordLoginAttempt(c, req.Username, validPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+	if !validPassword {
+		c.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	if server.passwordHasher.NeedsRehash(user.HashedPassword) {
+		err = server.rehashPassword(c.Request.Context(), user, req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
+	}
+
+	if user.IsTotpEnabled {
+		server.startLoginChallenge(c, user)
+		return
+	}
+
+	server.startSession(c, user)
+}
+
+// rehashPassword replaces a hash made with an outdated algorithm or parameters while the password is at hand,
+// it leaves the hash alone if the password has been changed in the meantime
+func (server *Server) rehashPassword(ctx context.Context, user db.User, password string) error {
+	hashedPassword, err := server.passwordHasher.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		HashedPassword:    hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+}
+
+// startSession issues the access and refresh tokens of a fully authenticated user
+func (server *Server) startSession(c *gin.Context, user db.User) {
+	// both tokens are bound to the session, the refresh token can only renew access tokens while it is active
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	err = util.CheckPasswordHash(req.Password, user.HashedPassword)
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateSessionToken(user.Username, user.Role, sessionID, token.TokenTypeRefresh, server.config.RefreshTokenDuration)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, errorResponse(err))
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(req.Username, server.config.AccessTokenDuration)
+	accessToken, accessPayload, err := server.tokenMaker.CreateSessionToken(user.Username, user.Role, sessionID, token.TokenTypeAccess, server.config.AccessTokenDuration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	session, err := server.store.CreateSession(c.Request.Context(), db.CreateSessionParams{
+		ID:           sessionID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    c.Request.UserAgent(),
+		ClientIp:     c.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiresAt,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiresAt,
+		User:                  newUserResponse(user),
 	}
 
 	c.JSON(http.StatusOK, rsp)
 }
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (server *Server) logoutUser(c *gin.Context) {
+	var req logoutUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+	sessionIDs := []uuid.UUID{authPayload.SessionID}
+
+	if req.RefreshToken != "" {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if refreshPayload.TokenType != token.TokenTypeRefresh {
+			err = errors.New("token is not a refresh token")
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if refreshPayload.Username != authPayload.Username {
+			err = errors.New("refresh token does not belong to authenticated user")
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		err = server.denylist.RevokeToken(c.Request.Context(), refreshPayload)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if refreshPayload.SessionID != authPayload.SessionID {
+			sessionIDs = append(sessionIDs, refreshPayload.SessionID)
+		}
+	}
+
+	// blocking the session stops its refresh token and every access token renewed with it
+	for _, sessionID := range sessionIDs {
+		err := server.store.BlockSession(c.Request.Context(), sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err := server.denylist.RevokeToken(c.Request.Context(), authPayload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// logoutAll revokes every token, session and API key of the authenticated user. The time is cut to whole
+// seconds like the issue time of a JWT, so a token issued right after the logout in the same second stays valid
+func (server *Server) logoutAll(c *gin.Context) {
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	err := server.denylist.RevokeAllTokens(c.Request.Context(), authPayload.Username, time.Now().Truncate(time.Second))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.LogoutAllTx(c.Request.Context(), authPayload.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -2,29 +2,39 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
 	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/notifier"
+	"github.com/hanifsyahsn/simple_bank/token"
 	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
-type eqCreateUserParamsMatcher struct {
+type eqCreateUserTxParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
 }
 
-func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
-	arg, ok := x.(db.CreateUserParams)
+func (e eqCreateUserTxParamsMatcher) Matches(x interface{}) bool {
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -32,18 +42,37 @@ func (e eqCreateUserParamsMatcher) Matches(x interface{}) bool {
 	if err != nil {
 		return false
 	}
+	if arg.SecretCodeHash == "" {
+		return false
+	}
 
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, arg)
-
+	return reflect.DeepEqual(e.arg, arg.CreateUserParams)
 }
 
-func (e eqCreateUserParamsMatcher) String() string {
+func (e eqCreateUserTxParamsMatcher) String() string {
 	return fmt.Sprintf("matches arg %v and password %v", e.arg, e.password)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{arg, password}
+func EqCreateUserTxParams(arg db.CreateUserParams, password string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{arg, password}
+}
+
+// createUserTx stands in for CreateUserTx, returning the verification code it would have stored
+func createUserTx(user db.User) func(ctx context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+	return func(ctx context.Context, arg db.CreateUserTxParams) (db.CreateUserTxResult, error) {
+		result := db.CreateUserTxResult{
+			User: user,
+			VerifyEmail: db.VerifyEmail{
+				ID:             util.RandomInt(1, 1000),
+				Username:       user.Username,
+				Email:          user.Email,
+				SecretCodeHash: arg.SecretCodeHash,
+				ExpiredAt:      arg.ExpiredAt,
+			},
+		}
+		return result, nil
+	}
 }
 
 func TestCreateUserAPI(t *testing.T) {
@@ -54,7 +83,7 @@ func TestCreateUserAPI(t *testing.T) {
 		createUserParams func(req createUserRequest) db.CreateUserParams
 		user             func(request createUserRequest) db.User
 		buildStubs       func(store *mockdb.MockStore, user db.User, arg db.CreateUserParams, password string)
-		checkResponse    func(t *testing.T, recorder *httptest.ResponseRecorder, user db.User)
+		checkResponse    func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, user db.User)
 	}{
 		{
 			name: "success",
@@ -81,10 +110,62 @@ func TestCreateUserAPI(t *testing.T) {
 				}
 			},
 			buildStubs: func(store *mockdb.MockStore, user db.User, arg db.CreateUserParams, password string) {
-				store.EXPECT().CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).Times(1).Return(user, nil)
+				store.EXPECT().CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).Times(1).DoAndReturn(createUserTx(user))
 			},
-			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, user db.User) {
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, user db.User) {
 				checkResponseMatcher(t, recorder, user)
+
+				messages := server.notifier.(*notifier.MemoryNotifier).Messages(user.Email)
+				require.Len(t, messages, 1)
+				require.Contains(t, messages[0].Body, server.config.VerifyEmailURL+"?email_id=")
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			body: createUserRequest{
+				Username: util.RandomOwner(),
+				Password: util.RandomString(6),
+				FullName: util.RandomOwner(),
+				Email:    util.RandomEmail(),
+			},
+			createUserParams: func(req createUserRequest) db.CreateUserParams {
+				return db.CreateUserParams{
+					Username: req.Username,
+					FullName: req.FullName,
+					Email:    req.Email,
+				}
+			},
+			user: func(request createUserRequest) db.User {
+				return db.User{}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, arg db.CreateUserParams, password string) {
+				store.EXPECT().CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).Times(1).
+					Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, user db.User) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "BreachedPassword",
+			body: createUserRequest{
+				Username: util.RandomOwner(),
+				Password: "Password1",
+				FullName: util.RandomOwner(),
+				Email:    util.RandomEmail(),
+			},
+			createUserParams: func(req createUserRequest) db.CreateUserParams {
+				return db.CreateUserParams{}
+			},
+			user: func(request createUserRequest) db.User {
+				return db.User{}
+			},
+			buildStubs: func(store *mockdb.MockStore, user db.User, arg db.CreateUserParams, password string) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder, user db.User) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.JSONEq(t, `{"Password":"Password has appeared in a data breach, choose another one"}`, recorder.Body.String())
 			},
 		},
 	}
@@ -117,11 +198,52 @@ func TestCreateUserAPI(t *testing.T) {
 
 			server.router.ServeHTTP(recorder, request)
 
-			tc.checkResponse(t, recorder, user)
+			tc.checkResponse(t, server, recorder, user)
 		})
 	}
 }
 
+// failingNotifier is a notifier that cannot deliver any message
+type failingNotifier struct{}
+
+func (failingNotifier) Send(_ context.Context, _ notifier.Message) error {
+	return errors.New("cannot send message")
+}
+
+func TestCreateUserAPIEmailNotSent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	req := createUserRequest{
+		Username: util.RandomOwner(),
+		Password: util.RandomString(6),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+	}
+	user := db.User{
+		Username: req.Username,
+		Email:    req.Email,
+		FullName: req.FullName,
+	}
+
+	// the user has been committed by the time the email is sent, so the sign up still succeeds
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).DoAndReturn(createUserTx(user))
+
+	server := newTestServer(t, store)
+	server.notifier = failingNotifier{}
+
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users", bytes.NewReader(body))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusCreated, recorder.Code)
+}
+
 func randomUser(t *testing.T) (user db.User, password string) {
 	password = util.RandomString(6)
 	hashedPassword, err := util.HashPassword(password)
@@ -132,6 +254,7 @@ func randomUser(t *testing.T) (user db.User, password string) {
 		HashedPassword: hashedPassword,
 		FullName:       util.RandomOwner(),
 		Email:          util.RandomEmail(),
+		Role:           util.DepositorRole,
 	}
 	return
 }
@@ -151,3 +274,457 @@ func checkResponseMatcher(t *testing.T, recorder *httptest.ResponseRecorder, use
 	require.WithinDuration(t, user.PasswordChangedAt, gotUserResponse.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user.CreatedAt, gotUserResponse.CreatedAt, time.Second)
 }
+
+func TestLoginUserAPI(t *testing.T) {
+	user, password := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  true,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+						return db.Session{
+							ID:           arg.ID,
+							Username:     arg.Username,
+							RefreshToken: arg.RefreshToken,
+							ExpiresAt:    arg.ExpiresAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotZero(t, rsp.SessionID)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.True(t, rsp.RefreshTokenExpiresAt.After(rsp.AccessTokenExpiresAt))
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "UserNotFound",
+			body: gin.H{
+				"username": "notfound",
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: "notfound",
+					ClientIp: "192.0.2.1",
+					Success:  false,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// an unknown user cannot be told apart from a wrong password
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.JSONEq(t, `{"error":"invalid username or password"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "IncorrectPassword",
+			body: gin.H{
+				"username": user.Username,
+				"password": "incorrect",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Eq(db.CreateLoginAttemptParams{
+					Username: user.Username,
+					ClientIp: "192.0.2.1",
+					Success:  false,
+				})).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.JSONEq(t, `{"error":"invalid username or password"}`, recorder.Body.String())
+			},
+		},
+		{
+			name: "RehashOutdatedHash",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				hasher, err := util.NewBcryptHasher(bcrypt.MinCost)
+				require.NoError(t, err)
+				legacyUser := user
+				legacyUser.HashedPassword, err = hasher.HashPassword(password)
+				require.NoError(t, err)
+
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(legacyUser, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().RehashUserPassword(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.RehashUserPasswordParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, legacyUser.HashedPassword, arg.OldHashedPassword)
+						require.True(t, strings.HasPrefix(arg.HashedPassword, "$argon2id$"))
+						require.NoError(t, util.CheckPasswordHash(password, arg.HashedPassword))
+						return nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{FailedAttempts: 5, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{FailedAttempts: 5, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "60", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "DelayedAfterFailure",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{FailedAttempts: 2, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{FailedAttempts: 2, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "2", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			name: "ClientIPLockedOut",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginFailuresByUsername(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByUsernameRow{}, nil)
+				store.EXPECT().GetLoginFailuresByClientIP(gomock.Any(), gomock.Any()).Times(1).
+					Return(db.GetLoginFailuresByClientIPRow{FailedAttempts: 20, LastFailedAt: time.Now()}, nil)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "TwoFactorRequired",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				twoFactorUser := user
+				twoFactorUser.IsTotpEnabled = true
+
+				expectLoginAllowed(store)
+				// the attempt is only recorded once the code has been checked
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(twoFactorUser, nil)
+				store.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateLoginChallengeParams) (db.LoginChallenge, error) {
+						require.Equal(t, user.Username, arg.Username)
+						return db.LoginChallenge{
+							ID:        arg.ID,
+							Username:  arg.Username,
+							ExpiresAt: arg.ExpiresAt,
+						}, nil
+					})
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.TwoFactorRequired)
+				require.NotZero(t, rsp.ChallengeToken)
+				require.True(t, rsp.ChallengeExpiresAt.After(time.Now()))
+				require.NotContains(t, recorder.Body.String(), "access_token")
+			},
+		},
+		{
+			name: "InternalErrorOnCreateSession",
+			body: gin.H{
+				"username": user.Username,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginAllowed(store)
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateLoginAttempt(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginAttempt{}, nil)
+				store.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(1).Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidUsername",
+			body: gin.H{
+				"username": "invalid-user#1",
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			body, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(body))
+			require.NoError(t, err)
+			request.RemoteAddr = "192.0.2.1:1234"
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          func(refreshToken string) gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore, refreshPayload *token.Payload)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, refreshPayload *token.Payload)
+	}{
+		{
+			name: "OK",
+			body: func(refreshToken string) gin.H {
+				return gin.H{}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Not(gomock.Eq(refreshPayload.SessionID))).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+
+				revoked, err := server.denylist.IsRevoked(context.Background(), refreshPayload)
+				require.NoError(t, err)
+				require.False(t, revoked)
+			},
+		},
+		{
+			name: "OKWithRefreshToken",
+			body: func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Not(gomock.Eq(refreshPayload.SessionID))).Times(1).Return(nil)
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Eq(refreshPayload.SessionID)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+
+				revoked, err := server.denylist.IsRevoked(context.Background(), refreshPayload)
+				require.NoError(t, err)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name: "RefreshTokenOfOtherUser",
+			body: func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other", util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalErrorOnBlockSession",
+			body: func(refreshToken string) gin.H {
+				return gin.H{"refresh_token": refreshToken}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+				store.EXPECT().BlockSession(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: func(refreshToken string) gin.H {
+				return gin.H{}
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore, refreshPayload *token.Payload) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, server *Server, refreshPayload *token.Payload) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, refreshPayload, err := server.tokenMaker.CreateSessionToken(user.Username, util.DepositorRole, uuid.New(), token.TokenTypeRefresh, time.Hour)
+			require.NoError(t, err)
+			tc.buildStubs(store, refreshPayload)
+
+			body, err := json.Marshal(tc.body(refreshToken))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/logout", bytes.NewReader(body))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server, refreshPayload)
+		})
+	}
+}
+
+func TestLogoutAllAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LogoutAllTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+				require.True(t, revoked)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().LogoutAllTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, revoked bool) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			otherPayload, err := token.NewPayload(user.Username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			otherPayload.IssuedAt = otherPayload.IssuedAt.Add(-time.Second)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/users/logout_all", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+
+			revoked, err := server.denylist.IsRevoked(context.Background(), otherPayload)
+			require.NoError(t, err)
+			tc.checkResponse(t, recorder, revoked)
+
+			// a JWT issued right after the logout carries an issue time cut to the second
+			newPayload, err := token.NewPayload(user.Username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+			newPayload.IssuedAt = newPayload.IssuedAt.Truncate(time.Second)
+
+			revoked, err = server.denylist.IsRevoked(context.Background(), newPayload)
+			require.NoError(t, err)
+			require.False(t, revoked)
+		})
+	}
+}
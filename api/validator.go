@@ -12,3 +12,11 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 
 	return false
 }
+
+var validScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedScope(scope)
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/notifier"
+	"github.com/hanifsyahsn/simple_bank/token"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+// verifyEmailCodeSize is the number of random bytes of an email verification code
+const verifyEmailCodeSize = 32
+
+// sendVerifyEmail sends the link that verifies the email of the user with the secret code
+func (server *Server) sendVerifyEmail(ctx context.Context, user db.User, verifyEmail db.VerifyEmail, secretCode string) error {
+	query := url.Values{}
+	query.Set("email_id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("secret_code", secretCode)
+
+	return server.notifier.Send(ctx, notifier.Message{
+		To:      verifyEmail.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hello %s,\nOpen this link to verify your email: %s?%s\nIt expires at %s.",
+			user.FullName, server.config.VerifyEmailURL, query.Encode(), verifyEmail.ExpiredAt.Format(time.RFC1123)),
+	})
+}
+
+// resendVerifyEmail sends a new verification link to the authenticated user, earlier links stay valid until they expire
+func (server *Server) resendVerifyEmail(c *gin.Context) {
+	authPayload := c.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(c.Request.Context(), authPayload.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsEmailVerified {
+		err := errors.New("email address has already been verified")
+		c.JSON(http.StatusConflict, errorResponse(err))
+		return
+	}
+
+	secretCode, err := util.GenerateSecureToken(verifyEmailCodeSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(c.Request.Context(), db.CreateVerifyEmailParams{
+		Username:       user.Username,
+		Email:          user.Email,
+		SecretCodeHash: util.HashSecureToken(secretCode),
+		ExpiredAt:      time.Now().Add(server.config.VerifyEmailDuration),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.sendVerifyEmail(c.Request.Context(), user, verifyEmail, secretCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(c.Request.Context(), db.VerifyEmailTxParams{
+		EmailID:        req.EmailID,
+		SecretCodeHash: util.HashSecureToken(req.SecretCode),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidVerifyEmail) {
+			c.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, verifyEmailResponse{IsVerified: user.IsEmailVerified})
+}
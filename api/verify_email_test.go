@@ -0,0 +1,211 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/notifier"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	emailID := util.RandomInt(1, 1000)
+	secretCode := util.RandomString(32)
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			query: url.Values{
+				"email_id":    {fmt.Sprint(emailID)},
+				"secret_code": {secretCode},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.VerifyEmailTxParams{
+					EmailID:        emailID,
+					SecretCodeHash: util.HashSecureToken(secretCode),
+				}
+				verifiedUser := user
+				verifiedUser.IsEmailVerified = true
+
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Eq(arg)).Times(1).Return(verifiedUser, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp verifyEmailResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.IsVerified)
+			},
+		},
+		{
+			name: "InvalidCode",
+			query: url.Values{
+				"email_id":    {fmt.Sprint(emailID)},
+				"secret_code": {secretCode},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, db.ErrInvalidVerifyEmail)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			query: url.Values{
+				"email_id":    {fmt.Sprint(emailID)},
+				"secret_code": {secretCode},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+		{
+			name: "MissingSecretCode",
+			query: url.Values{
+				"email_id": {fmt.Sprint(emailID)},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmailID",
+			query: url.Values{
+				"email_id":    {"0"},
+				"secret_code": {secretCode},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().VerifyEmailTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/verify_email?"+tc.query.Encode(), nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResendVerifyEmailAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Accepted",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, user.Email, arg.Email)
+						require.NotEmpty(t, arg.SecretCodeHash)
+						return db.VerifyEmail{
+							ID:             1,
+							Username:       arg.Username,
+							Email:          arg.Email,
+							SecretCodeHash: arg.SecretCodeHash,
+							ExpiredAt:      arg.ExpiredAt,
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				messages := server.notifier.(*notifier.MemoryNotifier).Messages(user.Email)
+				require.Len(t, messages, 1)
+				require.Contains(t, messages[0].Body, server.config.VerifyEmailURL+"?email_id=1&secret_code=")
+			},
+		},
+		{
+			name: "AlreadyVerified",
+			buildStubs: func(store *mockdb.MockStore) {
+				verifiedUser := user
+				verifiedUser.IsEmailVerified = true
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verifiedUser, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().CreateVerifyEmail(gomock.Any(), gomock.Any()).Times(1).Return(db.VerifyEmail{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Empty(t, server.notifier.(*notifier.MemoryNotifier).Messages(user.Email))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, "/users/verify_email", nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
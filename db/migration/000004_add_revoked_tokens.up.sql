@@ -0,0 +1,19 @@
+CREATE TABLE "revoked_tokens" (
+                                  "id" uuid PRIMARY KEY,
+                                  "username" varchar NOT NULL,
+                                  "expires_at" timestamptz NOT NULL,
+                                  "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "user_token_revocations" (
+                                          "username" varchar PRIMARY KEY,
+                                          "revoked_before" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "revoked_tokens" ("expires_at");
+
+COMMENT ON COLUMN "user_token_revocations"."revoked_before" IS 'tokens issued before this time are revoked';
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "user_token_revocations" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
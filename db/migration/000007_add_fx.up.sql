@@ -0,0 +1,29 @@
+CREATE TABLE "fx_quotes" (
+                             "id" uuid PRIMARY KEY,
+                             "username" varchar NOT NULL,
+                             "from_currency" varchar NOT NULL,
+                             "to_currency" varchar NOT NULL,
+                             "rate" numeric NOT NULL,
+                             "expires_at" timestamptz NOT NULL,
+                             "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "fx_quotes"."rate" IS 'units of to_currency per unit of from_currency';
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+
+ALTER TABLE "transfers" ADD COLUMN "quote_id" uuid UNIQUE;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'must be positive, in the currency of from_account_id';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'must be positive, in the currency of to_account_id';
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("quote_id") REFERENCES "fx_quotes" ("id");
@@ -0,0 +1,10 @@
+-- the system user owns the settlement accounts, its password hash matches no password so nobody can log in as it
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email")
+VALUES ('system', '!', 'Settlement', 'system@simplebank.invalid');
+
+-- one settlement account per currency, opened with the negated sum of the existing balances so every currency sums to zero
+INSERT INTO "accounts" ("owner", "balance", "currency", "overdraft_limit")
+SELECT 'system', -COALESCE(SUM(a."balance"), 0), c."currency", 9223372036854775807
+FROM (VALUES ('USD'), ('EUR'), ('CAD')) AS c("currency")
+LEFT JOIN "accounts" a ON a."currency" = c."currency"
+GROUP BY c."currency";
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "holds";
+
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "available_balance_within_overdraft_limit";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "available_balance";
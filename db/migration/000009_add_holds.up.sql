@@ -0,0 +1,39 @@
+ALTER TABLE "accounts" ADD COLUMN "available_balance" bigint;
+
+UPDATE "accounts" SET "available_balance" = "balance";
+
+ALTER TABLE "accounts" ALTER COLUMN "available_balance" SET NOT NULL;
+
+COMMENT ON COLUMN "accounts"."available_balance" IS 'balance minus the amount reserved by authorized holds';
+
+ALTER TABLE "accounts" ADD CONSTRAINT "available_balance_within_overdraft_limit" CHECK ("available_balance" >= -"overdraft_limit");
+
+CREATE TABLE "holds" (
+                         "id" bigserial PRIMARY KEY,
+                         "account_id" bigint NOT NULL,
+                         "to_account_id" bigint NOT NULL,
+                         "amount" bigint NOT NULL,
+                         "captured_amount" bigint NOT NULL DEFAULT 0,
+                         "status" varchar NOT NULL DEFAULT 'authorized',
+                         "transfer_id" bigint,
+                         "expires_at" timestamptz NOT NULL,
+                         "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "holds" ("account_id");
+
+CREATE INDEX ON "holds" ("expires_at") WHERE "status" = 'authorized';
+
+COMMENT ON COLUMN "holds"."amount" IS 'must be positive, reserved on account_id while authorized';
+
+COMMENT ON COLUMN "holds"."status" IS 'authorized, captured, voided or expired';
+
+ALTER TABLE "holds" ADD CONSTRAINT "hold_amount_positive" CHECK ("amount" > 0);
+
+ALTER TABLE "holds" ADD CONSTRAINT "captured_amount_within_hold" CHECK ("captured_amount" BETWEEN 0 AND "amount");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,7 @@
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "actor";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reason";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "refunded_amount";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "reversal_of";
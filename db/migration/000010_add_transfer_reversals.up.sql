@@ -0,0 +1,21 @@
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "refunded_amount" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "transfers" ADD COLUMN "reason" varchar;
+
+ALTER TABLE "transfers" ADD COLUMN "actor" varchar;
+
+ALTER TABLE "transfers" ADD CONSTRAINT "refunded_amount_within_to_amount" CHECK ("refunded_amount" BETWEEN 0 AND "to_amount");
+
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'the transfer this one compensates';
+
+COMMENT ON COLUMN "transfers"."refunded_amount" IS 'part of to_amount already returned by reversals';
+
+COMMENT ON COLUMN "transfers"."actor" IS 'user who requested the reversal';
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("actor") REFERENCES "users" ("username");
+
+CREATE INDEX ON "transfers" ("reversal_of");
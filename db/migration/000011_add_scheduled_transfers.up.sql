@@ -0,0 +1,51 @@
+CREATE TABLE "scheduled_transfers" (
+                                       "id" bigserial PRIMARY KEY,
+                                       "owner" varchar NOT NULL,
+                                       "from_account_id" bigint NOT NULL,
+                                       "to_account_id" bigint NOT NULL,
+                                       "amount" bigint NOT NULL,
+                                       "schedule" varchar,
+                                       "next_run_at" timestamptz,
+                                       "status" varchar NOT NULL DEFAULT 'active',
+                                       "failed_attempts" int NOT NULL DEFAULT 0,
+                                       "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+                                           "id" bigserial PRIMARY KEY,
+                                           "scheduled_transfer_id" bigint NOT NULL,
+                                           "transfer_id" bigint,
+                                           "status" varchar NOT NULL,
+                                           "error" varchar,
+                                           "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfers" ("owner");
+
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "status" = 'active';
+
+CREATE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id");
+
+COMMENT ON COLUMN "scheduled_transfers"."amount" IS 'must be positive';
+
+COMMENT ON COLUMN "scheduled_transfers"."schedule" IS 'cron expression, null for a one-off transfer';
+
+COMMENT ON COLUMN "scheduled_transfers"."next_run_at" IS 'null once the transfer has completed or was cancelled';
+
+COMMENT ON COLUMN "scheduled_transfers"."status" IS 'active, suspended, completed or cancelled';
+
+COMMENT ON COLUMN "scheduled_transfers"."failed_attempts" IS 'runs failed for insufficient funds since the last successful one';
+
+COMMENT ON COLUMN "scheduled_transfer_runs"."status" IS 'succeeded or failed';
+
+ALTER TABLE "scheduled_transfers" ADD CONSTRAINT "scheduled_transfer_amount_positive" CHECK ("amount" > 0);
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
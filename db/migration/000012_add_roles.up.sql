@@ -0,0 +1,7 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'depositor';
+
+COMMENT ON COLUMN "users"."role" IS 'depositor, admin or auditor';
+
+ALTER TABLE "accounts" ADD COLUMN "is_frozen" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "accounts"."is_frozen" IS 'frozen accounts can neither send nor receive money';
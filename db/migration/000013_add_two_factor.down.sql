@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "login_challenges";
+
+DROP TABLE IF EXISTS "recovery_codes";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "is_totp_enabled";
+
+ALTER TABLE IF EXISTS "users" DROP COLUMN IF EXISTS "totp_secret";
@@ -0,0 +1,31 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" bytea;
+
+ALTER TABLE "users" ADD COLUMN "is_totp_enabled" boolean NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "users"."totp_secret" IS 'encrypted, only used once a code has been verified and is_totp_enabled is set';
+
+CREATE TABLE "recovery_codes" (
+                                  "id" bigserial PRIMARY KEY,
+                                  "username" varchar NOT NULL,
+                                  "code_hash" varchar NOT NULL,
+                                  "used_at" timestamptz,
+                                  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha-256 of the recovery code, each code can only be used once';
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "login_challenges" (
+                                    "id" uuid PRIMARY KEY,
+                                    "username" varchar NOT NULL,
+                                    "failed_attempts" int NOT NULL DEFAULT 0,
+                                    "expires_at" timestamptz NOT NULL,
+                                    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "login_challenges" IS 'second login step of users with two-factor authentication, the id is the challenge token';
+
+ALTER TABLE "login_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
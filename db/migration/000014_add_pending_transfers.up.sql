@@ -0,0 +1,39 @@
+CREATE TABLE "pending_transfers" (
+                                     "id" bigserial PRIMARY KEY,
+                                     "owner" varchar NOT NULL,
+                                     "from_account_id" bigint NOT NULL,
+                                     "to_account_id" bigint NOT NULL,
+                                     "amount" bigint NOT NULL,
+                                     "to_amount" bigint NOT NULL,
+                                     "exchange_rate" varchar NOT NULL,
+                                     "quote_id" uuid,
+                                     "status" varchar NOT NULL DEFAULT 'pending',
+                                     "failed_attempts" int NOT NULL DEFAULT 0,
+                                     "transfer_id" bigint,
+                                     "expires_at" timestamptz NOT NULL,
+                                     "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "pending_transfers" ("owner");
+
+COMMENT ON COLUMN "pending_transfers"."amount" IS 'must be positive, in the currency of from_account_id';
+
+COMMENT ON COLUMN "pending_transfers"."to_amount" IS 'must be positive, in the currency of to_account_id';
+
+COMMENT ON COLUMN "pending_transfers"."status" IS 'pending or confirmed';
+
+COMMENT ON COLUMN "pending_transfers"."failed_attempts" IS 'confirmations rejected for a wrong password or code';
+
+COMMENT ON COLUMN "pending_transfers"."transfer_id" IS 'the transfer executed once confirmed';
+
+ALTER TABLE "pending_transfers" ADD CONSTRAINT "pending_transfer_amount_positive" CHECK ("amount" > 0 AND "to_amount" > 0);
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("quote_id") REFERENCES "fx_quotes" ("id");
+
+ALTER TABLE "pending_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,16 @@
+CREATE TABLE "password_reset_tokens" (
+                                         "id" bigserial PRIMARY KEY,
+                                         "username" varchar NOT NULL,
+                                         "token_hash" varchar UNIQUE NOT NULL,
+                                         "expires_at" timestamptz NOT NULL,
+                                         "used_at" timestamptz,
+                                         "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "password_reset_tokens" ("username");
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'sha-256 of the reset token, the token itself is only sent to the user';
+
+COMMENT ON COLUMN "password_reset_tokens"."used_at" IS 'set once the token has been used or a newer password invalidated it';
+
+ALTER TABLE "password_reset_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,19 @@
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "verify_emails" (
+                                 "id" bigserial PRIMARY KEY,
+                                 "username" varchar NOT NULL,
+                                 "email" varchar NOT NULL,
+                                 "secret_code_hash" varchar NOT NULL,
+                                 "is_used" boolean NOT NULL DEFAULT false,
+                                 "created_at" timestamptz NOT NULL DEFAULT (now()),
+                                 "expired_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "verify_emails" ("username");
+
+COMMENT ON COLUMN "verify_emails"."email" IS 'the address the code was sent to, it only verifies the user while the user still has it';
+
+COMMENT ON COLUMN "verify_emails"."secret_code_hash" IS 'sha-256 of the secret code, the code itself is only sent by email';
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
@@ -0,0 +1,16 @@
+CREATE TABLE "login_attempts" (
+                                  "id" bigserial PRIMARY KEY,
+                                  "username" varchar NOT NULL,
+                                  "client_ip" varchar NOT NULL,
+                                  "user_agent" varchar NOT NULL,
+                                  "success" boolean NOT NULL,
+                                  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "login_attempts" ("username", "created_at");
+
+CREATE INDEX ON "login_attempts" ("client_ip", "created_at");
+
+COMMENT ON COLUMN "login_attempts"."username" IS 'the username that was tried, it is not a foreign key so attempts on unknown users are counted too';
+
+COMMENT ON COLUMN "login_attempts"."success" IS 'whether the password was correct, a pending 2FA challenge counts as a success';
@@ -0,0 +1,5 @@
+ALTER TABLE "sessions" ADD COLUMN "last_used_at" timestamptz NOT NULL DEFAULT (now());
+
+CREATE INDEX ON "sessions" ("username", "last_used_at");
+
+COMMENT ON COLUMN "sessions"."last_used_at" IS 'when an access token of the session was last used, or when the session was created';
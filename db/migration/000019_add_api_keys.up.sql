@@ -0,0 +1,21 @@
+CREATE TABLE "api_keys" (
+                            "id" bigserial PRIMARY KEY,
+                            "username" varchar NOT NULL,
+                            "name" varchar NOT NULL,
+                            "prefix" varchar UNIQUE NOT NULL,
+                            "key_hash" varchar NOT NULL,
+                            "scopes" varchar[] NOT NULL,
+                            "last_used_at" timestamptz,
+                            "revoked_at" timestamptz,
+                            "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "api_keys" ("username");
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'the public start of the key that identifies it, such as sbk_0123456789ab';
+
+COMMENT ON COLUMN "api_keys"."key_hash" IS 'sha-256 of the whole key, the key itself is only shown once when it is created';
+
+COMMENT ON COLUMN "api_keys"."scopes" IS 'what the key may do on behalf of the user, such as accounts:read or transfers:write';
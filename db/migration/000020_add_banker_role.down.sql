@@ -0,0 +1,3 @@
+UPDATE "users" SET "role" = 'depositor' WHERE "role" = 'banker';
+
+COMMENT ON COLUMN "users"."role" IS 'depositor, admin or auditor';
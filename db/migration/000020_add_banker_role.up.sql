@@ -0,0 +1 @@
+COMMENT ON COLUMN "users"."role" IS 'depositor, banker, admin or auditor';
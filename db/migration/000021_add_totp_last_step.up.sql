@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "totp_last_step" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "users"."totp_last_step" IS 'time step of the last accepted TOTP code, codes of this step or an earlier one are rejected';
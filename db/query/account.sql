@@ -1,8 +1,8 @@
 -- name: CreateAccount :one
 INSERT INTO accounts (
-    owner, balance, currency
+    owner, balance, available_balance, currency
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $2, $3
 )
 RETURNING *;
 
@@ -22,15 +22,39 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountsByIDs :many
+SELECT * FROM accounts
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY id;
+
 -- name: UpdateAccount :one
-UPDATE accounts SET balance = $2
+UPDATE accounts SET balance = $2, available_balance = available_balance + $2 - balance
 WHERE id = $1
 RETURNING *;
 
 -- name: AddAccountBalance :one
-UPDATE accounts SET balance = balance + sqlc.arg(amount)
+UPDATE accounts SET balance = balance + sqlc.arg(amount), available_balance = available_balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
     RETURNING *;
 
+-- name: AddAccountAvailableBalance :one
+UPDATE accounts SET available_balance = available_balance + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts SET overdraft_limit = sqlc.arg(overdraft_limit)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: DeleteAccount :exec
-DELETE FROM accounts WHERE id = $1;
\ No newline at end of file
+DELETE FROM accounts WHERE id = $1;
+
+-- name: GetSettlementAccount :one
+SELECT * FROM accounts
+WHERE owner = 'system' AND currency = $1 LIMIT 1;
+
+-- name: SetAccountFrozen :one
+UPDATE accounts SET is_frozen = sqlc.arg(is_frozen)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,38 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+    username,
+    name,
+    prefix,
+    key_hash,
+    scopes
+) VALUES (
+             $1, $2, $3, $4, $5
+         ) RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE id = $1 LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+WHERE username = $1 AND revoked_at IS NULL
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: RevokeAPIKey :exec
+UPDATE api_keys SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserAPIKeys :exec
+UPDATE api_keys SET revoked_at = now()
+WHERE username = $1 AND revoked_at IS NULL;
+
+-- name: UseAPIKey :one
+UPDATE api_keys SET last_used_at = now()
+FROM users
+WHERE api_keys.prefix = $1
+  AND api_keys.key_hash = $2
+  AND api_keys.revoked_at IS NULL
+  AND users.username = api_keys.username
+RETURNING api_keys.*, users.role;
@@ -15,4 +15,35 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
     LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListAccountStatementEntries :many
+-- running_balance is the account balance right after the entry was applied,
+-- derived backwards from the current balance so it also holds for accounts
+-- that were opened with a non-zero balance.
+WITH ledger AS (
+    SELECT e.id, e.account_id, e.amount, e.created_at,
+           (a.balance - SUM(e.amount) OVER () + SUM(e.amount) OVER (ORDER BY e.id))::bigint AS running_balance
+    FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE e.account_id = sqlc.arg(account_id)
+)
+SELECT * FROM ledger
+WHERE (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
+ORDER BY id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: GetAccountStatementBalances :one
+SELECT
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE sqlc.narg(start_time)::timestamptz IS NULL OR e.created_at >= sqlc.narg(start_time)
+    ), 0))::bigint AS opening_balance,
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE sqlc.narg(end_time)::timestamptz IS NOT NULL AND e.created_at >= sqlc.narg(end_time)
+    ), 0))::bigint AS closing_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = sqlc.arg(account_id)
+GROUP BY a.id, a.balance;
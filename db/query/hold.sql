@@ -0,0 +1,48 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+    account_id,
+    to_account_id,
+    amount,
+    expires_at
+) VALUES (
+             $1, $2, $3, $4
+         ) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListHolds :many
+SELECT * FROM holds
+WHERE account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateHold :one
+UPDATE holds SET
+    status = sqlc.arg(status),
+    captured_amount = sqlc.arg(captured_amount),
+    transfer_id = sqlc.narg(transfer_id)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ExpireHolds :execrows
+-- ExpireHolds marks every authorized hold past its expiry as expired and releases the reserved amount
+WITH expired AS (
+    UPDATE holds SET status = 'expired'
+    WHERE status = 'authorized' AND expires_at <= now()
+    RETURNING account_id, amount
+), released AS (
+    SELECT account_id, SUM(amount)::bigint AS amount
+    FROM expired
+    GROUP BY account_id
+)
+UPDATE accounts SET available_balance = accounts.available_balance + released.amount
+FROM released
+WHERE accounts.id = released.account_id;
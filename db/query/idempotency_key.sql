@@ -0,0 +1,13 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    username,
+    key,
+    request_hash,
+    response_body
+) VALUES (
+             $1, $2, $3, $4
+         ) RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 LIMIT 1;
\ No newline at end of file
@@ -0,0 +1,32 @@
+-- name: CreateLoginAttempt :one
+INSERT INTO login_attempts (
+    username,
+    client_ip,
+    user_agent,
+    success
+) VALUES (
+             $1, $2, $3, $4
+         ) RETURNING *;
+
+-- name: GetLoginFailuresByUsername :one
+SELECT count(*) AS failed_attempts,
+       COALESCE(max(created_at), to_timestamp(0))::timestamptz AS last_failed_at
+FROM login_attempts
+WHERE username = sqlc.arg(username) AND NOT success AND created_at > sqlc.arg(since)
+  AND created_at > COALESCE((
+    SELECT max(created_at) FROM login_attempts
+    WHERE username = sqlc.arg(username) AND success
+), to_timestamp(0));
+
+-- name: GetLoginFailuresByClientIP :one
+SELECT count(*) AS failed_attempts,
+       COALESCE(max(created_at), to_timestamp(0))::timestamptz AS last_failed_at
+FROM login_attempts
+WHERE client_ip = sqlc.arg(client_ip) AND NOT success AND created_at > sqlc.arg(since);
+
+-- name: ListLoginAttempts :many
+SELECT * FROM login_attempts
+WHERE username = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,34 @@
+-- name: CreatePendingTransfer :one
+INSERT INTO pending_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    to_amount,
+    exchange_rate,
+    quote_id,
+    expires_at
+) VALUES (
+             $1, $2, $3, $4, $5, $6, $7, $8
+         ) RETURNING *;
+
+-- name: GetPendingTransfer :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetPendingTransferForUpdate :one
+SELECT * FROM pending_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: AddPendingTransferFailedAttempt :one
+UPDATE pending_transfers SET failed_attempts = failed_attempts + 1
+WHERE id = $1
+RETURNING *;
+
+-- name: ConfirmPendingTransfer :one
+UPDATE pending_transfers SET
+    status = 'confirmed',
+    transfer_id = $2
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,68 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    owner,
+    from_account_id,
+    to_account_id,
+    amount,
+    schedule,
+    next_run_at
+) VALUES (
+             $1, $2, $3, $4, $5, $6
+         ) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetScheduledTransferForUpdate :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListDueScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_run_at <= now()
+ORDER BY next_run_at
+LIMIT $1;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers SET
+    amount = COALESCE(sqlc.narg(amount), amount),
+    schedule = COALESCE(sqlc.narg(schedule), schedule),
+    next_run_at = COALESCE(sqlc.narg(next_run_at), next_run_at),
+    status = COALESCE(sqlc.narg(status), status),
+    failed_attempts = COALESCE(sqlc.narg(failed_attempts), failed_attempts)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetScheduledTransferState :one
+UPDATE scheduled_transfers SET
+    next_run_at = sqlc.narg(next_run_at),
+    status = sqlc.arg(status),
+    failed_attempts = sqlc.arg(failed_attempts)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+    scheduled_transfer_id,
+    transfer_id,
+    status,
+    error
+) VALUES (
+             $1, $2, $3, $4
+         ) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
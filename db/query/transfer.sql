@@ -2,20 +2,43 @@
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    exchange_rate,
+    quote_id,
+    reversal_of,
+    reason,
+    actor
 ) VALUES (
-             $1, $2, $3
+             $1, $2, $3, $4, $5, $6, $7, $8, $9
          ) RETURNING *;
 
 -- name: GetTransfer :one
 SELECT * FROM transfers
 WHERE id = $1 LIMIT 1;
 
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: AddTransferRefundedAmount :one
+UPDATE transfers
+SET refunded_amount = refunded_amount + sqlc.arg(amount)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: ListTransfers :many
 SELECT * FROM transfers
 WHERE
-        from_account_id = $1 OR
-        to_account_id = $2
+    (
+        (sqlc.arg(direction)::text IN ('out', 'both') AND from_account_id = sqlc.arg(account_id)) OR
+        (sqlc.arg(direction)::text IN ('in', 'both') AND to_account_id = sqlc.arg(account_id))
+    )
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR amount >= sqlc.narg(min_amount))
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR amount <= sqlc.narg(max_amount))
+    AND (sqlc.narg(start_time)::timestamptz IS NULL OR created_at >= sqlc.narg(start_time))
+    AND (sqlc.narg(end_time)::timestamptz IS NULL OR created_at < sqlc.narg(end_time))
 ORDER BY id
-    LIMIT $3
-OFFSET $4;
\ No newline at end of file
+    LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
\ No newline at end of file
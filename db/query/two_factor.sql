@@ -0,0 +1,38 @@
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    code_hash
+) VALUES (
+             $1, $2
+         ) RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+RETURNING *;
+
+-- name: CreateLoginChallenge :one
+INSERT INTO login_challenges (
+    id,
+    username,
+    expires_at
+) VALUES (
+             $1, $2, $3
+         ) RETURNING *;
+
+-- name: GetLoginChallenge :one
+SELECT * FROM login_challenges
+WHERE id = $1 LIMIT 1;
+
+-- name: AddLoginChallengeFailedAttempt :one
+UPDATE login_challenges SET failed_attempts = failed_attempts + 1
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges
+WHERE id = $1;
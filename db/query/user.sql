@@ -8,4 +8,37 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
\ No newline at end of file
+WHERE username = $1 LIMIT 1;
+
+-- name: SetUserTOTPSecret :one
+UPDATE users SET totp_secret = sqlc.arg(totp_secret), is_totp_enabled = false
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users SET is_totp_enabled = true
+WHERE username = $1 AND totp_secret IS NOT NULL
+RETURNING *;
+
+-- name: UseTOTPStep :one
+UPDATE users SET totp_last_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND totp_last_step < sqlc.arg(step)
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users SET hashed_password = sqlc.arg(hashed_password), password_changed_at = now()
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users SET is_email_verified = true
+WHERE username = sqlc.arg(username) AND email = sqlc.arg(email)
+RETURNING *;
+
+-- name: RehashUserPassword :exec
+UPDATE users SET hashed_password = sqlc.arg(hashed_password)
+WHERE username = sqlc.arg(username) AND hashed_password = sqlc.arg(old_hashed_password);
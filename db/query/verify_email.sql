@@ -0,0 +1,19 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+    username,
+    email,
+    secret_code_hash,
+    expired_at
+) VALUES (
+             $1, $2, $3, $4
+         ) RETURNING *;
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT * FROM verify_emails
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UseVerifyEmail :one
+UPDATE verify_emails SET is_used = true
+WHERE id = $1
+RETURNING *;
@@ -7,12 +7,41 @@ package db
 
 import (
 	"context"
+
+	"github.com/lib/pq"
 )
 
+const addAccountAvailableBalance = `-- name: AddAccountAvailableBalance :one
+UPDATE accounts SET available_balance = available_balance + $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen
+`
+
+type AddAccountAvailableBalanceParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddAccountAvailableBalance(ctx context.Context, arg AddAccountAvailableBalanceParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, addAccountAvailableBalance, arg.Amount, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
 const addAccountBalance = `-- name: AddAccountBalance :one
-UPDATE accounts SET balance = balance + $1
+UPDATE accounts SET balance = balance + $1, available_balance = available_balance + $1
 WHERE id = $2
-    RETURNING id, owner, balance, currency, created_at
+    RETURNING id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen
 `
 
 type AddAccountBalanceParams struct {
@@ -29,17 +58,20 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (
-    owner, balance, currency
+    owner, balance, available_balance, currency
 ) VALUES (
-    $1, $2, $3
+    $1, $2, $2, $3
 )
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen
 `
 
 type CreateAccountParams struct {
@@ -57,6 +89,9 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
 	)
 	return i, err
 }
@@ -71,7 +106,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -84,12 +119,15 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -103,12 +141,36 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
+const getSettlementAccount = `-- name: GetSettlementAccount :one
+SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts
+WHERE owner = 'system' AND currency = $1 LIMIT 1
+`
+
+func (q *Queries) GetSettlementAccount(ctx context.Context, currency string) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getSettlementAccount, currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
 	)
 	return i, err
 }
 
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -136,6 +198,49 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.AvailableBalance,
+			&i.IsFrozen,
+			&i.IsFrozen,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsByIDs = `-- name: ListAccountsByIDs :many
+SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts
+WHERE id = ANY($1::bigint[])
+ORDER BY id
+`
+
+func (q *Queries) ListAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.OverdraftLimit,
+			&i.AvailableBalance,
+			&i.IsFrozen,
+			&i.IsFrozen,
 		); err != nil {
 			return nil, err
 		}
@@ -150,10 +255,37 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 	return items, nil
 }
 
+const setAccountFrozen = `-- name: SetAccountFrozen :one
+UPDATE accounts SET is_frozen = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen
+`
+
+type SetAccountFrozenParams struct {
+	IsFrozen bool  `json:"is_frozen"`
+	ID       int64 `json:"id"`
+}
+
+func (q *Queries) SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, setAccountFrozen, arg.IsFrozen, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
-UPDATE accounts SET balance = $2
+UPDATE accounts SET balance = $2, available_balance = available_balance + $2 - balance
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen
 `
 
 type UpdateAccountParams struct {
@@ -170,6 +302,36 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
+	)
+	return i, err
+}
+
+const updateAccountOverdraftLimit = `-- name: UpdateAccountOverdraftLimit :one
+UPDATE accounts SET overdraft_limit = $1
+WHERE id = $2
+RETURNING id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen
+`
+
+type UpdateAccountOverdraftLimitParams struct {
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	ID             int64 `json:"id"`
+}
+
+func (q *Queries) UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, updateAccountOverdraftLimit, arg.OverdraftLimit, arg.ID)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.OverdraftLimit,
+		&i.AvailableBalance,
+		&i.IsFrozen,
 	)
 	return i, err
 }
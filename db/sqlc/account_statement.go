@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+type AccountStatementParams struct {
+	AccountID int64        `json:"account_id"`
+	StartTime sql.NullTime `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+	Limit     int32        `json:"limit"`
+	Offset    int32        `json:"offset"`
+}
+
+type AccountStatementResult struct {
+	OpeningBalance int64                            `json:"opening_balance"`
+	ClosingBalance int64                            `json:"closing_balance"`
+	Entries        []ListAccountStatementEntriesRow `json:"entries"`
+}
+
+// AccountStatement reads the entries and the opening/closing balances of an account
+// from a single snapshot, so a transfer committing in between cannot make them disagree
+func (store *SQLStore) AccountStatement(ctx context.Context, arg AccountStatementParams) (AccountStatementResult, error) {
+	var result AccountStatementResult
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := store.execTxWithOptions(ctx, opts, func(queries *Queries) error {
+		var err error
+
+		balances, err := queries.GetAccountStatementBalances(ctx, GetAccountStatementBalancesParams{
+			StartTime: arg.StartTime,
+			EndTime:   arg.EndTime,
+			AccountID: arg.AccountID,
+		})
+		if err != nil {
+			return err
+		}
+		result.OpeningBalance = balances.OpeningBalance
+		result.ClosingBalance = balances.ClosingBalance
+
+		result.Entries, err = queries.ListAccountStatementEntries(ctx, ListAccountStatementEntriesParams{
+			AccountID: arg.AccountID,
+			StartTime: arg.StartTime,
+			EndTime:   arg.EndTime,
+			Limit:     arg.Limit,
+			Offset:    arg.Offset,
+		})
+		return err
+	})
+
+	return result, err
+}
@@ -13,10 +13,14 @@ import (
 )
 
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomMoney())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64) Account {
 	user := createRandomUser(t)
 	arg := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
+		Balance:  balance,
 		Currency: util.RandomCurrency(),
 	}
 
@@ -28,6 +32,9 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Balance, account.Balance)
 	require.Equal(t, arg.Currency, account.Currency)
 
+	require.Zero(t, account.OverdraftLimit)
+	require.Equal(t, arg.Balance, account.AvailableBalance)
+
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
 
@@ -64,10 +71,56 @@ func TestUpdateAccount(t *testing.T) {
 	require.Equal(t, account.ID, accountUpdate.ID)
 	require.Equal(t, accountUpdate.Owner, account.Owner)
 	require.Equal(t, accountUpdate.Balance, arg.Balance)
+	require.Equal(t, accountUpdate.AvailableBalance, arg.Balance)
 	require.Equal(t, accountUpdate.Currency, account.Currency)
 	require.WithinDuration(t, account.CreatedAt, accountUpdate.CreatedAt, time.Second)
 }
 
+func TestUpdateAccountOverdraftLimit(t *testing.T) {
+	account := createRandomAccount(t)
+	arg := UpdateAccountOverdraftLimitParams{
+		ID:             account.ID,
+		OverdraftLimit: util.RandomMoney(),
+	}
+	accountUpdate, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, accountUpdate)
+
+	require.Equal(t, account.ID, accountUpdate.ID)
+	require.Equal(t, account.Balance, accountUpdate.Balance)
+	require.Equal(t, arg.OverdraftLimit, accountUpdate.OverdraftLimit)
+}
+
+func TestSetAccountFrozen(t *testing.T) {
+	account := createRandomAccount(t)
+	require.False(t, account.IsFrozen)
+
+	frozenAccount, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{
+		ID:       account.ID,
+		IsFrozen: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, frozenAccount.ID)
+	require.True(t, frozenAccount.IsFrozen)
+
+	unfrozenAccount, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{
+		ID:       account.ID,
+		IsFrozen: false,
+	})
+	require.NoError(t, err)
+	require.False(t, unfrozenAccount.IsFrozen)
+}
+
+func TestUpdateAccountBalanceBeyondOverdraftLimit(t *testing.T) {
+	account := createRandomAccount(t)
+	arg := UpdateAccountParams{
+		ID:      account.ID,
+		Balance: -1,
+	}
+	_, err := testQueries.UpdateAccount(context.Background(), arg)
+	require.Error(t, err)
+}
+
 func TestDeleteAccount(t *testing.T) {
 	account := createRandomAccount(t)
 	accountDeletedError := testQueries.DeleteAccount(context.Background(), account.ID)
@@ -113,26 +166,26 @@ func TestGetListAccounts_Error(t *testing.T) {
 		Offset: 5,
 	}
 
-	mock.ExpectQuery("SELECT id, owner, balance, currency, created_at FROM accounts").
+	mock.ExpectQuery("SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts").
 		WillReturnError(fmt.Errorf("query error"))
 
 	_, err = queries.ListAccounts(context.Background(), arg)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "query error")
 
-	rows := sqlmock.NewRows([]string{"id", "owner", "balance", "currency", "created_at"}).
-		AddRow("1", "ownerName", "theBalance", "theCurrency", "theCreatedAt")
-	mock.ExpectQuery("SELECT id, owner, balance, currency, created_at FROM accounts").
+	rows := sqlmock.NewRows([]string{"id", "owner", "balance", "currency", "created_at", "overdraft_limit", "available_balance", "is_frozen"}).
+		AddRow("1", "ownerName", "theBalance", "theCurrency", "theCreatedAt", "theOverdraftLimit", "theAvailableBalance", "theIsFrozen")
+	mock.ExpectQuery("SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts").
 		WillReturnRows(rows)
 
 	_, err = queries.ListAccounts(context.Background(), arg)
 	require.Error(t, err)
 
-	rows = sqlmock.NewRows([]string{"id", "owner", "balance", "currency", "created_at"}).
-		AddRow(1, "owner", 100, "USD", time.Now()).
+	rows = sqlmock.NewRows([]string{"id", "owner", "balance", "currency", "created_at", "overdraft_limit", "available_balance", "is_frozen"}).
+		AddRow(1, "owner", 100, "USD", time.Now(), 0, 100, false).
 		RowError(0, fmt.Errorf("iteration error"))
 
-	mock.ExpectQuery("SELECT id, owner, balance, currency, created_at FROM accounts").
+	mock.ExpectQuery("SELECT id, owner, balance, currency, created_at, overdraft_limit, available_balance, is_frozen FROM accounts").
 		WillReturnRows(rows)
 
 	_, err = queries.ListAccounts(context.Background(), arg)
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User) (ApiKey, string) {
+	key, prefix, err := util.GenerateAPIKey()
+	require.NoError(t, err)
+
+	arg := CreateAPIKeyParams{
+		Username: user.Username,
+		Name:     util.RandomString(10),
+		Prefix:   prefix,
+		KeyHash:  util.HashSecureToken(key),
+		Scopes:   []string{util.ScopeAccountsRead, util.ScopeTransfersWrite},
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, apiKey.ID)
+	require.Equal(t, arg.Username, apiKey.Username)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.KeyHash, apiKey.KeyHash)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey, key
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomAPIKey(t, user)
+}
+
+func TestGetAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey, _ := createRandomAPIKey(t, user)
+
+	apiKey2, err := testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.Prefix, apiKey2.Prefix)
+	require.Equal(t, apiKey.Scopes, apiKey2.Scopes)
+	require.WithinDuration(t, apiKey.CreatedAt, apiKey2.CreatedAt, time.Second)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	user := createRandomUser(t)
+	revoked, _ := createRandomAPIKey(t, user)
+	older, _ := createRandomAPIKey(t, user)
+	newer, _ := createRandomAPIKey(t, user)
+
+	err := testQueries.RevokeAPIKey(context.Background(), revoked.ID)
+	require.NoError(t, err)
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), ListAPIKeysParams{
+		Username: user.Username,
+		Limit:    10,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+	require.Equal(t, newer.ID, apiKeys[0].ID)
+	require.Equal(t, older.ID, apiKeys[1].ID)
+}
+
+func TestUseAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey, key := createRandomAPIKey(t, user)
+
+	used, err := testQueries.UseAPIKey(context.Background(), UseAPIKeyParams{
+		Prefix:  apiKey.Prefix,
+		KeyHash: util.HashSecureToken(key),
+	})
+	require.NoError(t, err)
+	require.Equal(t, apiKey.ID, used.ID)
+	require.Equal(t, user.Role, used.Role)
+	require.Equal(t, apiKey.Scopes, used.Scopes)
+	require.True(t, used.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), used.LastUsedAt.Time, time.Second)
+
+	// the prefix alone is not enough
+	_, err = testQueries.UseAPIKey(context.Background(), UseAPIKeyParams{
+		Prefix:  apiKey.Prefix,
+		KeyHash: util.HashSecureToken(apiKey.Prefix + "_" + util.RandomString(43)),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = testQueries.RevokeAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.UseAPIKey(context.Background(), UseAPIKeyParams{
+		Prefix:  apiKey.Prefix,
+		KeyHash: util.HashSecureToken(key),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
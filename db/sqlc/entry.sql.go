@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
@@ -93,3 +95,105 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const getAccountStatementBalances = `-- name: GetAccountStatementBalances :one
+SELECT
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE $1::timestamptz IS NULL OR e.created_at >= $1
+    ), 0))::bigint AS opening_balance,
+    (a.balance - COALESCE(SUM(e.amount) FILTER (
+        WHERE $2::timestamptz IS NOT NULL AND e.created_at >= $2
+    ), 0))::bigint AS closing_balance
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $3
+GROUP BY a.id, a.balance
+`
+
+type GetAccountStatementBalancesParams struct {
+	StartTime sql.NullTime `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+	AccountID int64        `json:"account_id"`
+}
+
+type GetAccountStatementBalancesRow struct {
+	OpeningBalance int64 `json:"opening_balance"`
+	ClosingBalance int64 `json:"closing_balance"`
+}
+
+func (q *Queries) GetAccountStatementBalances(ctx context.Context, arg GetAccountStatementBalancesParams) (GetAccountStatementBalancesRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountStatementBalances, arg.StartTime, arg.EndTime, arg.AccountID)
+	var i GetAccountStatementBalancesRow
+	err := row.Scan(&i.OpeningBalance, &i.ClosingBalance)
+	return i, err
+}
+
+const listAccountStatementEntries = `-- name: ListAccountStatementEntries :many
+WITH ledger AS (
+    SELECT e.id, e.account_id, e.amount, e.created_at,
+           (a.balance - SUM(e.amount) OVER () + SUM(e.amount) OVER (ORDER BY e.id))::bigint AS running_balance
+    FROM entries e
+    JOIN accounts a ON a.id = e.account_id
+    WHERE e.account_id = $1
+)
+SELECT id, account_id, amount, created_at, running_balance FROM ledger
+WHERE ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+ORDER BY id
+LIMIT $4
+OFFSET $5
+`
+
+type ListAccountStatementEntriesParams struct {
+	AccountID int64        `json:"account_id"`
+	StartTime sql.NullTime `json:"start_time"`
+	EndTime   sql.NullTime `json:"end_time"`
+	Limit     int32        `json:"limit"`
+	Offset    int32        `json:"offset"`
+}
+
+type ListAccountStatementEntriesRow struct {
+	ID             int64     `json:"id"`
+	AccountID      int64     `json:"account_id"`
+	Amount         int64     `json:"amount"`
+	CreatedAt      time.Time `json:"created_at"`
+	RunningBalance int64     `json:"running_balance"`
+}
+
+// running_balance is the account balance right after the entry was applied,
+// derived backwards from the current balance so it also holds for accounts
+// that were opened with a non-zero balance.
+func (q *Queries) ListAccountStatementEntries(ctx context.Context, arg ListAccountStatementEntriesParams) ([]ListAccountStatementEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatementEntries,
+		arg.AccountID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountStatementEntriesRow{}
+	for rows.Next() {
+		var i ListAccountStatementEntriesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.RunningBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
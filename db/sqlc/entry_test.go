@@ -109,3 +109,34 @@ func TestGetListEntries_Error(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "iteration error")
 }
+
+func TestListAccountStatementEntries_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	queries := New(db)
+
+	arg := ListAccountStatementEntriesParams{
+		AccountID: 1,
+		Limit:     5,
+		Offset:    0,
+	}
+
+	mock.ExpectQuery("WITH ledger AS").
+		WillReturnError(fmt.Errorf("query error"))
+
+	_, err = queries.ListAccountStatementEntries(context.Background(), arg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "query error")
+
+	rows := mock.NewRows([]string{"id", "account_id", "amount", "created_at", "running_balance"}).
+		AddRow(1, 1, -10, time.Now(), 90).
+		RowError(0, fmt.Errorf("iteration error"))
+	mock.ExpectQuery("WITH ledger AS").
+		WillReturnRows(rows)
+
+	_, err = queries.ListAccountStatementEntries(context.Background(), arg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "iteration error")
+}
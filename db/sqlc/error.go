@@ -0,0 +1,22 @@
+package db
+
+import "errors"
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+var ErrIdempotencyKeyConflict = errors.New("idempotency key has already been used")
+var ErrQuoteAlreadyUsed = errors.New("exchange rate quote has already been used")
+var ErrHoldNotActive = errors.New("hold is no longer authorized")
+var ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+var ErrTransferAlreadyReversed = errors.New("transfer has already been fully reversed")
+var ErrReversalNotReversible = errors.New("a reversal cannot be reversed")
+var ErrSettlementNotReversible = errors.New("a deposit or withdrawal cannot be reversed")
+var ErrRefundExceedsTransfer = errors.New("refund amount exceeds the un-refunded remainder of the transfer")
+var ErrRefundTooSmall = errors.New("refund amount is too small to convert back")
+var ErrScheduledTransferNotDue = errors.New("scheduled transfer is no longer due")
+var ErrAccountFrozen = errors.New("account is frozen")
+var ErrPendingTransferConfirmed = errors.New("pending transfer has already been confirmed")
+var ErrPendingTransferExpired = errors.New("pending transfer has expired")
+var ErrPendingTransferTooManyAttempts = errors.New("too many failed attempts to confirm the pending transfer")
+var ErrPendingTransferInvalidCredentials = errors.New("invalid password or code")
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+var ErrInvalidVerifyEmail = errors.New("invalid or expired email verification code")
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomFxQuote(t *testing.T, user User, from, to string) FxQuote {
+	arg := CreateFxQuoteParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         "0.92",
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}
+
+	quote, err := testQueries.CreateFxQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, quote)
+
+	require.Equal(t, arg.ID, quote.ID)
+	require.Equal(t, arg.Username, quote.Username)
+	require.Equal(t, arg.FromCurrency, quote.FromCurrency)
+	require.Equal(t, arg.ToCurrency, quote.ToCurrency)
+	require.Equal(t, arg.Rate, quote.Rate)
+	require.WithinDuration(t, arg.ExpiresAt, quote.ExpiresAt, time.Second)
+	require.NotZero(t, quote.CreatedAt)
+
+	return quote
+}
+
+func TestCreateFxQuote(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomFxQuote(t, user, util.USD, util.EUR)
+}
+
+func TestGetFxQuote(t *testing.T) {
+	user := createRandomUser(t)
+	quote := createRandomFxQuote(t, user, util.USD, util.EUR)
+
+	getQuote, err := testQueries.GetFxQuote(context.Background(), quote.ID)
+	require.NoError(t, err)
+	require.Equal(t, quote.ID, getQuote.ID)
+	require.Equal(t, quote.Rate, getQuote.Rate)
+	require.WithinDuration(t, quote.ExpiresAt, getQuote.ExpiresAt, time.Second)
+}
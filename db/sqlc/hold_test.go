@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomHold(t *testing.T, account1, account2 Account, expiresAt time.Time) Hold {
+	arg := CreateHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      util.RandomInt(1, 1000),
+		ExpiresAt:   expiresAt,
+	}
+
+	hold, err := testQueries.CreateHold(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, hold)
+
+	require.Equal(t, arg.AccountID, hold.AccountID)
+	require.Equal(t, arg.ToAccountID, hold.ToAccountID)
+	require.Equal(t, arg.Amount, hold.Amount)
+	require.Zero(t, hold.CapturedAmount)
+	require.Equal(t, HoldStatusAuthorized, hold.Status)
+	require.False(t, hold.TransferID.Valid)
+	require.WithinDuration(t, arg.ExpiresAt, hold.ExpiresAt, time.Second)
+	require.NotZero(t, hold.ID)
+	require.NotZero(t, hold.CreatedAt)
+
+	return hold
+}
+
+func TestCreateHold(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	createRandomHold(t, account1, account2, time.Now().Add(time.Hour))
+}
+
+func TestGetHold(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	hold := createRandomHold(t, account1, account2, time.Now().Add(time.Hour))
+
+	getHold, err := testQueries.GetHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, hold.ID, getHold.ID)
+	require.Equal(t, hold.Amount, getHold.Amount)
+	require.Equal(t, hold.Status, getHold.Status)
+}
+
+func TestListHolds(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		createRandomHold(t, account1, account2, time.Now().Add(time.Hour))
+	}
+
+	holds, err := testQueries.ListHolds(context.Background(), ListHoldsParams{
+		AccountID: account1.ID,
+		Limit:     3,
+		Offset:    2,
+	})
+	require.NoError(t, err)
+	require.Len(t, holds, 3)
+	for _, hold := range holds {
+		require.Equal(t, account1.ID, hold.AccountID)
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccount(t)
+
+	expiredHold, err := store.AuthorizeHold(context.Background(), AuthorizeHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      100,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	activeHold, err := store.AuthorizeHold(context.Background(), AuthorizeHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      200,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.ExpireHolds(context.Background())
+	require.NoError(t, err)
+
+	hold, err := testQueries.GetHold(context.Background(), expiredHold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusExpired, hold.Status)
+
+	hold, err = testQueries.GetHold(context.Background(), activeHold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusAuthorized, hold.Status)
+
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), account.Balance)
+	require.Equal(t, int64(800), account.AvailableBalance)
+}
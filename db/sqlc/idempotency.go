@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// IdempotencyParams identifies a client retry-safe request whose response must be stored with the transaction
+type IdempotencyParams struct {
+	Username    string `json:"username"`
+	Key         string `json:"key"`
+	RequestHash string `json:"request_hash"`
+}
+
+// saveIdempotentResponse stores the response of an idempotent request inside the running transaction,
+// so the response is only kept if the transaction commits
+func saveIdempotentResponse(ctx context.Context, q *Queries, arg *IdempotencyParams, response any) error {
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:     arg.Username,
+		Key:          arg.Key,
+		RequestHash:  arg.RequestHash,
+		ResponseBody: responseBody,
+	})
+	if err != nil {
+		var e *pq.Error
+		if errors.As(err, &e) && e.Code.Name() == "unique_violation" {
+			return ErrIdempotencyKeyConflict
+		}
+		return err
+	}
+	return nil
+}
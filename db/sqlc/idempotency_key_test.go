@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomIdempotencyKey(t *testing.T, user User) IdempotencyKey {
+	arg := CreateIdempotencyKeyParams{
+		Username:     user.Username,
+		Key:          util.RandomString(16),
+		RequestHash:  util.RandomString(64),
+		ResponseBody: json.RawMessage(`{"id":1}`),
+	}
+
+	idempotencyKey, err := testQueries.CreateIdempotencyKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, idempotencyKey)
+
+	require.Equal(t, arg.Username, idempotencyKey.Username)
+	require.Equal(t, arg.Key, idempotencyKey.Key)
+	require.Equal(t, arg.RequestHash, idempotencyKey.RequestHash)
+	require.JSONEq(t, string(arg.ResponseBody), string(idempotencyKey.ResponseBody))
+	require.NotZero(t, idempotencyKey.CreatedAt)
+
+	return idempotencyKey
+}
+
+func TestCreateIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomIdempotencyKey(t, user)
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	user := createRandomUser(t)
+	idempotencyKey := createRandomIdempotencyKey(t, user)
+
+	result, err := testQueries.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: user.Username,
+		Key:      idempotencyKey.Key,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, result)
+
+	require.Equal(t, idempotencyKey.Username, result.Username)
+	require.Equal(t, idempotencyKey.Key, result.Key)
+	require.Equal(t, idempotencyKey.RequestHash, result.RequestHash)
+	require.JSONEq(t, string(idempotencyKey.ResponseBody), string(result.ResponseBody))
+	require.WithinDuration(t, idempotencyKey.CreatedAt, result.CreatedAt, time.Second)
+}
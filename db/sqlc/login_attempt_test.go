@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomLoginAttempt(t *testing.T, username string, clientIP string, success bool) LoginAttempt {
+	arg := CreateLoginAttemptParams{
+		Username:  username,
+		ClientIp:  clientIP,
+		UserAgent: util.RandomString(10),
+		Success:   success,
+	}
+
+	attempt, err := testQueries.CreateLoginAttempt(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, attempt.ID)
+	require.Equal(t, arg.Username, attempt.Username)
+	require.Equal(t, arg.ClientIp, attempt.ClientIp)
+	require.Equal(t, arg.UserAgent, attempt.UserAgent)
+	require.Equal(t, arg.Success, attempt.Success)
+	require.NotZero(t, attempt.CreatedAt)
+
+	return attempt
+}
+
+func randomClientIP() string {
+	return fmt.Sprintf("10.%d.%d.%d", util.RandomInt(0, 255), util.RandomInt(0, 255), util.RandomInt(0, 255))
+}
+
+func TestGetLoginFailuresByUsername(t *testing.T) {
+	// attempts on unknown usernames are counted too
+	username := util.RandomOwner()
+	since := time.Now().Add(-time.Minute)
+
+	failures, err := testQueries.GetLoginFailuresByUsername(context.Background(), GetLoginFailuresByUsernameParams{
+		Username: username,
+		Since:    since,
+	})
+	require.NoError(t, err)
+	require.Zero(t, failures.FailedAttempts)
+
+	createRandomLoginAttempt(t, username, randomClientIP(), false)
+	last := createRandomLoginAttempt(t, username, randomClientIP(), false)
+
+	failures, err = testQueries.GetLoginFailuresByUsername(context.Background(), GetLoginFailuresByUsernameParams{
+		Username: username,
+		Since:    since,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), failures.FailedAttempts)
+	require.WithinDuration(t, last.CreatedAt, failures.LastFailedAt, time.Millisecond)
+
+	// a successful login starts the count over
+	createRandomLoginAttempt(t, username, randomClientIP(), true)
+
+	failures, err = testQueries.GetLoginFailuresByUsername(context.Background(), GetLoginFailuresByUsernameParams{
+		Username: username,
+		Since:    since,
+	})
+	require.NoError(t, err)
+	require.Zero(t, failures.FailedAttempts)
+}
+
+func TestGetLoginFailuresByClientIP(t *testing.T) {
+	clientIP := randomClientIP()
+
+	createRandomLoginAttempt(t, util.RandomOwner(), clientIP, false)
+	createRandomLoginAttempt(t, util.RandomOwner(), clientIP, true)
+	last := createRandomLoginAttempt(t, util.RandomOwner(), clientIP, false)
+
+	failures, err := testQueries.GetLoginFailuresByClientIP(context.Background(), GetLoginFailuresByClientIPParams{
+		ClientIp: clientIP,
+		Since:    time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), failures.FailedAttempts)
+	require.WithinDuration(t, last.CreatedAt, failures.LastFailedAt, time.Millisecond)
+
+	failures, err = testQueries.GetLoginFailuresByClientIP(context.Background(), GetLoginFailuresByClientIPParams{
+		ClientIp: clientIP,
+		Since:    time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Zero(t, failures.FailedAttempts)
+}
+
+func TestListLoginAttempts(t *testing.T) {
+	user := createRandomUser(t)
+
+	var lastAttempt LoginAttempt
+	for i := 0; i < 5; i++ {
+		lastAttempt = createRandomLoginAttempt(t, user.Username, randomClientIP(), i%2 == 0)
+	}
+
+	attempts, err := testQueries.ListLoginAttempts(context.Background(), ListLoginAttemptsParams{
+		Username: user.Username,
+		Limit:    3,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, attempts, 3)
+	require.Equal(t, lastAttempt.ID, attempts[0].ID)
+	for _, attempt := range attempts {
+		require.Equal(t, user.Username, attempt.Username)
+	}
+}
@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +18,27 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far the balance may go below zero
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// balance minus the amount reserved by authorized holds
+	AvailableBalance int64 `json:"available_balance"`
+	// frozen accounts can neither send nor receive money
+	IsFrozen bool `json:"is_frozen"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// the public start of the key that identifies it, such as sbk_0123456789ab
+	Prefix string `json:"prefix"`
+	// sha-256 of the whole key, the key itself is only shown once when it is created
+	KeyHash string `json:"key_hash"`
+	// what the key may do on behalf of the user, such as accounts:read or transfers:write
+	Scopes     []string     `json:"scopes"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	CreatedAt  time.Time    `json:"created_at"`
 }
 
 type Entry struct {
@@ -24,13 +49,167 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type FxQuote struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	// units of to_currency per unit of from_currency
+	Rate      string    `json:"rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Hold struct {
+	ID          int64 `json:"id"`
+	AccountID   int64 `json:"account_id"`
+	ToAccountID int64 `json:"to_account_id"`
+	// must be positive, reserved on account_id while authorized
+	Amount         int64 `json:"amount"`
+	CapturedAmount int64 `json:"captured_amount"`
+	// authorized, captured, voided or expired
+	Status     string        `json:"status"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username string `json:"username"`
+	Key      string `json:"key"`
+	// sha256 of the method, route and request body
+	RequestHash  string          `json:"request_hash"`
+	ResponseBody json.RawMessage `json:"response_body"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	ID int64 `json:"id"`
+	// the username that was tried, it is not a foreign key so attempts on unknown users are counted too
+	Username  string `json:"username"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	// whether the password was correct, a pending 2FA challenge counts as a success
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// second login step of users with two-factor authentication, the id is the challenge token
+type LoginChallenge struct {
+	ID             uuid.UUID `json:"id"`
+	Username       string    `json:"username"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha-256 of the reset token, the token itself is only sent to the user
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// set once the token has been used or a newer password invalidated it
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type PendingTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// must be positive, in the currency of from_account_id
+	Amount int64 `json:"amount"`
+	// must be positive, in the currency of to_account_id
+	ToAmount     int64         `json:"to_amount"`
+	ExchangeRate string        `json:"exchange_rate"`
+	QuoteID      uuid.NullUUID `json:"quote_id"`
+	// pending or confirmed
+	Status string `json:"status"`
+	// confirmations rejected for a wrong password or code
+	FailedAttempts int32 `json:"failed_attempts"`
+	// the transfer executed once confirmed
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha-256 of the recovery code, each code can only be used once
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64  `json:"id"`
+	Owner         string `json:"owner"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	// must be positive
+	Amount int64 `json:"amount"`
+	// cron expression, null for a one-off transfer
+	Schedule sql.NullString `json:"schedule"`
+	// null once the transfer has completed or was cancelled
+	NextRunAt sql.NullTime `json:"next_run_at"`
+	// active, suspended, completed or cancelled
+	Status string `json:"status"`
+	// runs failed for insufficient funds since the last successful one
+	FailedAttempts int32     `json:"failed_attempts"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64         `json:"id"`
+	ScheduledTransferID int64         `json:"scheduled_transfer_id"`
+	TransferID          sql.NullInt64 `json:"transfer_id"`
+	// succeeded or failed
+	Status    string         `json:"status"`
+	Error     sql.NullString `json:"error"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	// when an access token of the session was last used, or when the session was created
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// must be positive
+	// must be positive, in the currency of from_account_id
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// must be positive, in the currency of to_account_id
+	ToAmount     int64         `json:"to_amount"`
+	ExchangeRate string        `json:"exchange_rate"`
+	QuoteID      uuid.NullUUID `json:"quote_id"`
+	// the transfer this one compensates
+	ReversalOf sql.NullInt64 `json:"reversal_of"`
+	// part of to_amount already returned by reversals
+	RefundedAmount int64          `json:"refunded_amount"`
+	Reason         sql.NullString `json:"reason"`
+	// user who requested the reversal
+	Actor sql.NullString `json:"actor"`
 }
 
 type User struct {
@@ -40,4 +219,30 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// depositor, banker, admin or auditor
+	Role string `json:"role"`
+	// encrypted, only used once a code has been verified and is_totp_enabled is set
+	TotpSecret      []byte `json:"totp_secret"`
+	IsTotpEnabled   bool   `json:"is_totp_enabled"`
+	IsEmailVerified bool   `json:"is_email_verified"`
+	// time step of the last accepted TOTP code, codes of this step or an earlier one are rejected
+	TotpLastStep int64 `json:"totp_last_step"`
+}
+
+type UserTokenRevocation struct {
+	Username string `json:"username"`
+	// tokens issued before this time are revoked
+	RevokedBefore time.Time `json:"revoked_before"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// the address the code was sent to, it only verifies the user while the user still has it
+	Email string `json:"email"`
+	// sha-256 of the secret code, the code itself is only sent by email
+	SecretCodeHash string    `json:"secret_code_hash"`
+	IsUsed         bool      `json:"is_used"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiredAt      time.Time `json:"expired_at"`
 }
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPasswordResetToken(t *testing.T, user User, expiresAt time.Time) (PasswordResetToken, string) {
+	token, err := util.GenerateSecureToken(32)
+	require.NoError(t, err)
+
+	arg := CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecureToken(token),
+		ExpiresAt: expiresAt,
+	}
+
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, resetToken.Username)
+	require.Equal(t, arg.TokenHash, resetToken.TokenHash)
+	require.WithinDuration(t, arg.ExpiresAt, resetToken.ExpiresAt, time.Second)
+	require.False(t, resetToken.UsedAt.Valid)
+	require.NotZero(t, resetToken.CreatedAt)
+
+	return resetToken, token
+}
+
+func TestCountPasswordResetTokens(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomPasswordResetToken(t, user, time.Now().Add(time.Minute))
+	createRandomPasswordResetToken(t, user, time.Now().Add(time.Minute))
+
+	arg := CountPasswordResetTokensParams{
+		Username: user.Username,
+		Since:    time.Now().Add(-time.Minute),
+	}
+	count, err := testQueries.CountPasswordResetTokens(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	arg.Since = time.Now().Add(time.Minute)
+	count, err = testQueries.CountPasswordResetTokens(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	_, token := createRandomPasswordResetToken(t, user, time.Now().Add(time.Minute))
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: hashedPassword,
+	}
+
+	updatedUser, err := store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, updatedUser.HashedPassword)
+	require.WithinDuration(t, time.Now(), updatedUser.PasswordChangedAt, time.Second)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	// a reset token can only be used once
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
+
+func TestResetPasswordTxBeforeUpdate(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	_, token := createRandomPasswordResetToken(t, user, time.Now().Add(time.Minute))
+
+	arg := ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: user.HashedPassword,
+		BeforeUpdate: func(tokenUser User) error {
+			require.Equal(t, user.Username, tokenUser.Username)
+			return errors.New("rejected")
+		},
+	}
+
+	_, err := store.ResetPasswordTx(context.Background(), arg)
+	require.EqualError(t, err, "rejected")
+
+	// the token is not used up when BeforeUpdate fails
+	arg.BeforeUpdate = nil
+	_, err = store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+}
+
+func TestResetPasswordTxInvalidToken(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	_, expiredToken := createRandomPasswordResetToken(t, user, time.Now().Add(-time.Second))
+
+	for _, token := range []string{expiredToken, util.RandomString(43)} {
+		_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+			TokenHash:      util.HashSecureToken(token),
+			HashedPassword: user.HashedPassword,
+		})
+		require.ErrorIs(t, err, ErrInvalidResetToken)
+	}
+}
+
+func TestUpdatePasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	apiKey, _ := createRandomAPIKey(t, user)
+	_, token := createRandomPasswordResetToken(t, user, time.Now().Add(time.Minute))
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	updatedUser, err := store.UpdatePasswordTx(context.Background(), UpdatePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, updatedUser.HashedPassword)
+	require.True(t, updatedUser.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	apiKey, err = testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.True(t, apiKey.RevokedAt.Valid)
+
+	// the reset tokens issued before the change can no longer be used
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecureToken(token),
+		HashedPassword: hashedPassword,
+	})
+	require.ErrorIs(t, err, ErrInvalidResetToken)
+}
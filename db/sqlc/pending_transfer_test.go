@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomPendingTransfer(t *testing.T, account1, account2 Account, amount int64, expiresAt time.Time) PendingTransfer {
+	store := NewStore(testDB)
+
+	arg := CreatePendingTransferTxParams{
+		CreatePendingTransferParams: CreatePendingTransferParams{
+			Owner:         account1.Owner,
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+			ToAmount:      amount,
+			ExchangeRate:  "1",
+			ExpiresAt:     expiresAt,
+		},
+	}
+
+	pending, err := store.CreatePendingTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, pending)
+
+	require.Equal(t, arg.Owner, pending.Owner)
+	require.Equal(t, arg.FromAccountID, pending.FromAccountID)
+	require.Equal(t, arg.ToAccountID, pending.ToAccountID)
+	require.Equal(t, arg.Amount, pending.Amount)
+	require.Equal(t, arg.ToAmount, pending.ToAmount)
+	require.Equal(t, PendingTransferStatusPending, pending.Status)
+	require.Zero(t, pending.FailedAttempts)
+	require.False(t, pending.TransferID.Valid)
+	require.WithinDuration(t, arg.ExpiresAt, pending.ExpiresAt, time.Second)
+	require.NotZero(t, pending.CreatedAt)
+
+	return pending
+}
+
+// confirmPendingTransferParams confirms the pending transfer with valid or wrong credentials
+func confirmPendingTransferParams(id int64, valid bool) ConfirmPendingTransferTxParams {
+	return ConfirmPendingTransferTxParams{
+		ID:                id,
+		MaxFailedAttempts: 5,
+		CheckCredentials: func(pending PendingTransfer) (bool, error) {
+			return valid, nil
+		},
+	}
+}
+
+func TestConfirmPendingTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+	pending := createRandomPendingTransfer(t, account1, account2, 60, time.Now().Add(time.Minute))
+
+	// no money moves before the confirmation
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+
+	// a wrong password or code is counted and no money moves
+	_, err = store.ConfirmPendingTransferTx(context.Background(), confirmPendingTransferParams(pending.ID, false))
+	require.ErrorIs(t, err, ErrPendingTransferInvalidCredentials)
+
+	pending, err = testQueries.GetPendingTransfer(context.Background(), pending.ID)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), pending.FailedAttempts)
+	require.Equal(t, PendingTransferStatusPending, pending.Status)
+
+	result, err := store.ConfirmPendingTransferTx(context.Background(), confirmPendingTransferParams(pending.ID, true))
+	require.NoError(t, err)
+	require.Equal(t, int64(60), result.Transfer.Amount)
+	require.Equal(t, account1.Balance-60, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+60, result.ToAccount.Balance)
+
+	confirmed, err := testQueries.GetPendingTransfer(context.Background(), pending.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusConfirmed, confirmed.Status)
+	require.Equal(t, result.Transfer.ID, confirmed.TransferID.Int64)
+
+	// a pending transfer only runs once
+	_, err = store.ConfirmPendingTransferTx(context.Background(), confirmPendingTransferParams(pending.ID, true))
+	require.ErrorIs(t, err, ErrPendingTransferConfirmed)
+}
+
+func TestConfirmPendingTransferTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+	pending := createRandomPendingTransfer(t, account1, account2, 60, time.Now().Add(-time.Second))
+
+	_, err := store.ConfirmPendingTransferTx(context.Background(), confirmPendingTransferParams(pending.ID, true))
+	require.ErrorIs(t, err, ErrPendingTransferExpired)
+}
+
+func TestConfirmPendingTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 10)
+	account2 := createRandomAccount(t)
+	pending := createRandomPendingTransfer(t, account1, account2, 60, time.Now().Add(time.Minute))
+
+	_, err := store.ConfirmPendingTransferTx(context.Background(), confirmPendingTransferParams(pending.ID, true))
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	pending, err = testQueries.GetPendingTransfer(context.Background(), pending.ID)
+	require.NoError(t, err)
+	require.Equal(t, PendingTransferStatusPending, pending.Status)
+}
+
+func TestConfirmPendingTransferTxTooManyAttempts(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+	pending := createRandomPendingTransfer(t, account1, account2, 60, time.Now().Add(time.Minute))
+
+	arg := confirmPendingTransferParams(pending.ID, false)
+	arg.MaxFailedAttempts = 2
+	for i := 0; i < 2; i++ {
+		_, err := store.ConfirmPendingTransferTx(context.Background(), arg)
+		require.ErrorIs(t, err, ErrPendingTransferInvalidCredentials)
+	}
+
+	// the credentials are not even checked once the attempts are used up
+	arg.CheckCredentials = func(pending PendingTransfer) (bool, error) {
+		t.Fatal("credentials checked after too many failed attempts")
+		return true, nil
+	}
+	_, err := store.ConfirmPendingTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrPendingTransferTooManyAttempts)
+
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+}
@@ -6,24 +6,104 @@ package db
 
 import (
 	"context"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	AddAccountAvailableBalance(ctx context.Context, arg AddAccountAvailableBalanceParams) (Account, error)
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AddLoginChallengeFailedAttempt(ctx context.Context, id uuid.UUID) (LoginChallenge, error)
+	AddPendingTransferFailedAttempt(ctx context.Context, id int64) (PendingTransfer, error)
+	AddTransferRefundedAmount(ctx context.Context, arg AddTransferRefundedAmountParams) (Transfer, error)
+	BlockSession(ctx context.Context, id uuid.UUID) error
+	BlockUserSessions(ctx context.Context, username string) error
+	ConfirmPendingTransfer(ctx context.Context, arg ConfirmPendingTransferParams) (PendingTransfer, error)
+	CountPasswordResetTokens(ctx context.Context, arg CountPasswordResetTokensParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFxQuote(ctx context.Context, arg CreateFxQuoteParams) (FxQuote, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) (LoginAttempt, error)
+	CreateLoginChallenge(ctx context.Context, arg CreateLoginChallengeParams) (LoginChallenge, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreatePendingTransfer(ctx context.Context, arg CreatePendingTransferParams) (PendingTransfer, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteExpiredRevokedTokens(ctx context.Context) error
+	DeleteLoginChallenge(ctx context.Context, id uuid.UUID) error
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	EnableUserTOTP(ctx context.Context, username string) (User, error)
+	// ExpireHolds marks every authorized hold past its expiry as expired and releases the reserved amount
+	ExpireHolds(ctx context.Context) (int64, error)
+	GetAPIKey(ctx context.Context, id int64) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountStatementBalances(ctx context.Context, arg GetAccountStatementBalancesParams) (GetAccountStatementBalancesRow, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFxQuote(ctx context.Context, id uuid.UUID) (FxQuote, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetLoginChallenge(ctx context.Context, id uuid.UUID) (LoginChallenge, error)
+	GetLoginFailuresByClientIP(ctx context.Context, arg GetLoginFailuresByClientIPParams) (GetLoginFailuresByClientIPRow, error)
+	GetLoginFailuresByUsername(ctx context.Context, arg GetLoginFailuresByUsernameParams) (GetLoginFailuresByUsernameRow, error)
+	GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	GetPendingTransfer(ctx context.Context, id int64) (PendingTransfer, error)
+	GetPendingTransferForUpdate(ctx context.Context, id int64) (PendingTransfer, error)
+	GetSettlementAccount(ctx context.Context, currency string) (Account, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetVerifyEmailForUpdate(ctx context.Context, id int64) (VerifyEmail, error)
+	InvalidatePasswordResetTokens(ctx context.Context, username string) error
+	IsTokenRevoked(ctx context.Context, arg IsTokenRevokedParams) (bool, error)
+	ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error)
+	// running_balance is the account balance right after the entry was applied,
+	// derived backwards from the current balance so it also holds for accounts
+	// that were opened with a non-zero balance.
+	ListAccountStatementEntries(ctx context.Context, arg ListAccountStatementEntriesParams) ([]ListAccountStatementEntriesRow, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsByIDs(ctx context.Context, ids []int64) ([]Account, error)
+	ListActiveSessions(ctx context.Context, arg ListActiveSessionsParams) ([]Session, error)
+	ListDueScheduledTransfers(ctx context.Context, limit int32) ([]ScheduledTransfer, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error)
+	ListLoginAttempts(ctx context.Context, arg ListLoginAttemptsParams) ([]LoginAttempt, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error
+	RevokeAPIKey(ctx context.Context, id int64) error
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	RevokeUserAPIKeys(ctx context.Context, username string) error
+	RevokeUserTokens(ctx context.Context, arg RevokeUserTokensParams) error
+	SetAccountFrozen(ctx context.Context, arg SetAccountFrozenParams) (Account, error)
+	SetScheduledTransferState(ctx context.Context, arg SetScheduledTransferStateParams) (ScheduledTransfer, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	TouchSession(ctx context.Context, id uuid.UUID) (TouchSessionRow, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateHold(ctx context.Context, arg UpdateHoldParams) (Hold, error)
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UseAPIKey(ctx context.Context, arg UseAPIKeyParams) (UseAPIKeyRow, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error)
+	UseVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeToken(t *testing.T) {
+	user := createRandomUser(t)
+	issuedAt := time.Now()
+
+	arg := IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: issuedAt,
+	}
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = testQueries.RevokeToken(context.Background(), RevokeTokenParams{
+		ID:        arg.ID,
+		Username:  user.Username,
+		ExpiresAt: issuedAt.Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestRevokeUserTokens(t *testing.T) {
+	user := createRandomUser(t)
+	revokedBefore := time.Now()
+
+	err := testQueries.RevokeUserTokens(context.Background(), RevokeUserTokensParams{
+		Username:      user.Username,
+		RevokedBefore: revokedBefore,
+	})
+	require.NoError(t, err)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: revokedBefore.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: revokedBefore.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// an earlier cutoff must not move the existing one backwards
+	err = testQueries.RevokeUserTokens(context.Background(), RevokeUserTokensParams{
+		Username:      user.Username,
+		RevokedBefore: revokedBefore.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), IsTokenRevokedParams{
+		ID:       uuid.New(),
+		Username: user.Username,
+		IssuedAt: revokedBefore.Add(-time.Minute),
+	})
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
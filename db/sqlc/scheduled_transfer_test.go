@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, account1, account2 Account, nextRunAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Owner:         account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        util.RandomInt(1, 1000),
+		Schedule:      sql.NullString{String: "@monthly", Valid: true},
+		NextRunAt:     sql.NullTime{Time: nextRunAt, Valid: true},
+	}
+
+	scheduled, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, scheduled)
+
+	require.Equal(t, arg.Owner, scheduled.Owner)
+	require.Equal(t, arg.FromAccountID, scheduled.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduled.ToAccountID)
+	require.Equal(t, arg.Amount, scheduled.Amount)
+	require.Equal(t, arg.Schedule, scheduled.Schedule)
+	require.WithinDuration(t, arg.NextRunAt.Time, scheduled.NextRunAt.Time, time.Second)
+	require.Equal(t, ScheduledTransferStatusActive, scheduled.Status)
+	require.Zero(t, scheduled.FailedAttempts)
+	require.NotZero(t, scheduled.ID)
+	require.NotZero(t, scheduled.CreatedAt)
+
+	return scheduled
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	for i := 0; i < 5; i++ {
+		createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+	}
+
+	scheduled, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Owner:  account1.Owner,
+		Limit:  3,
+		Offset: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduled, 3)
+	for _, s := range scheduled {
+		require.Equal(t, account1.Owner, s.Owner)
+	}
+}
+
+func TestUpdateScheduledTransfer(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	scheduled := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+
+	updated, err := testQueries.UpdateScheduledTransfer(context.Background(), UpdateScheduledTransferParams{
+		ID:     scheduled.ID,
+		Status: sql.NullString{String: ScheduledTransferStatusSuspended, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferStatusSuspended, updated.Status)
+	require.Equal(t, scheduled.Amount, updated.Amount)
+	require.Equal(t, scheduled.Schedule, updated.Schedule)
+	require.WithinDuration(t, scheduled.NextRunAt.Time, updated.NextRunAt.Time, time.Second)
+}
+
+func TestListDueScheduledTransfers(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	due := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(-time.Minute))
+	notDue := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+
+	scheduled, err := testQueries.ListDueScheduledTransfers(context.Background(), 1000)
+	require.NoError(t, err)
+
+	ids := make(map[int64]bool)
+	for _, s := range scheduled {
+		require.Equal(t, ScheduledTransferStatusActive, s.Status)
+		ids[s.ID] = true
+	}
+	require.True(t, ids[due.ID])
+	require.False(t, ids[notDue.ID])
+}
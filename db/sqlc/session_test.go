@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, user User) Session {
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshToken, session.RefreshToken)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+	require.WithinDuration(t, session.CreatedAt, session.LastUsedAt, time.Second)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomSession(t, user)
+}
+
+func TestGetSession(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	sessionResult, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, sessionResult)
+
+	require.Equal(t, session.ID, sessionResult.ID)
+	require.Equal(t, session.Username, sessionResult.Username)
+	require.Equal(t, session.RefreshToken, sessionResult.RefreshToken)
+	require.Equal(t, session.IsBlocked, sessionResult.IsBlocked)
+	require.WithinDuration(t, session.ExpiresAt, sessionResult.ExpiresAt, time.Second)
+	require.WithinDuration(t, session.CreatedAt, sessionResult.CreatedAt, time.Second)
+}
+
+func TestBlockSession(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	err := testQueries.BlockSession(context.Background(), session.ID)
+	require.NoError(t, err)
+
+	sessionResult, err := testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, sessionResult.IsBlocked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	user := createRandomUser(t)
+	session1 := createRandomSession(t, user)
+	session2 := createRandomSession(t, user)
+
+	err := testQueries.BlockUserSessions(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	for _, session := range []Session{session1, session2} {
+		sessionResult, err := testQueries.GetSession(context.Background(), session.ID)
+		require.NoError(t, err)
+		require.True(t, sessionResult.IsBlocked)
+	}
+}
+
+func TestLogoutAllTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+	apiKey, _ := createRandomAPIKey(t, user)
+
+	otherUser := createRandomUser(t)
+	otherSession := createRandomSession(t, otherUser)
+	otherAPIKey, _ := createRandomAPIKey(t, otherUser)
+
+	err := store.LogoutAllTx(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	session, err = testQueries.GetSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	apiKey, err = testQueries.GetAPIKey(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+	require.True(t, apiKey.RevokedAt.Valid)
+
+	otherSession, err = testQueries.GetSession(context.Background(), otherSession.ID)
+	require.NoError(t, err)
+	require.False(t, otherSession.IsBlocked)
+
+	otherAPIKey, err = testQueries.GetAPIKey(context.Background(), otherAPIKey.ID)
+	require.NoError(t, err)
+	require.False(t, otherAPIKey.RevokedAt.Valid)
+}
+
+func TestTouchSession(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	touched, err := testQueries.TouchSession(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, touched.ID)
+	require.True(t, touched.LastUsedAt.After(session.LastUsedAt))
+	require.WithinDuration(t, user.PasswordChangedAt, touched.PasswordChangedAt, time.Second)
+
+	_, err = testQueries.TouchSession(context.Background(), uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListActiveSessions(t *testing.T) {
+	user := createRandomUser(t)
+	blocked := createRandomSession(t, user)
+	used := createRandomSession(t, user)
+	unused := createRandomSession(t, user)
+
+	err := testQueries.BlockSession(context.Background(), blocked.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.TouchSession(context.Background(), used.ID)
+	require.NoError(t, err)
+
+	sessions, err := testQueries.ListActiveSessions(context.Background(), ListActiveSessionsParams{
+		Username: user.Username,
+		Limit:    10,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.Equal(t, used.ID, sessions[0].ID)
+	require.Equal(t, unused.ID, sessions[1].ID)
+}
@@ -3,12 +3,36 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error)
+	AccountStatement(ctx context.Context, arg AccountStatementParams) (AccountStatementResult, error)
+	DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error)
+	WithdrawTx(ctx context.Context, arg WithdrawTxParams) (WithdrawTxResult, error)
+	AuthorizeHold(ctx context.Context, arg AuthorizeHoldParams) (Hold, error)
+	CaptureHold(ctx context.Context, arg CaptureHoldParams) (CaptureHoldResult, error)
+	VoidHold(ctx context.Context, holdID int64) (Hold, error)
+	ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error)
+	RunScheduledTransferTx(ctx context.Context, arg RunScheduledTransferTxParams) (RunScheduledTransferTxResult, error)
+	SuspendScheduledTransferTx(ctx context.Context, arg SuspendScheduledTransferTxParams) (RunScheduledTransferTxResult, error)
+	BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error)
+	EnrollTOTPTx(ctx context.Context, arg EnrollTOTPTxParams) (User, error)
+	CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferTxParams) (PendingTransfer, error)
+	ConfirmPendingTransferTx(ctx context.Context, arg ConfirmPendingTransferTxParams) (TransferTxResult, error)
+	UpdatePasswordTx(ctx context.Context, arg UpdatePasswordTxParams) (User, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	LogoutAllTx(ctx context.Context, username string) error
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error)
 }
 
 type SQLStore struct {
@@ -24,7 +48,11 @@ func NewStore(db *sql.DB) Store {
 }
 
 func (store *SQLStore) execTx(ctx context.Context, fn func(queries *Queries) error) error {
-	tx, err := store.db.BeginTx(ctx, nil)
+	return store.execTxWithOptions(ctx, nil, fn)
+}
+
+func (store *SQLStore) execTxWithOptions(ctx context.Context, opts *sql.TxOptions, fn func(queries *Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -44,6 +72,16 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// ToAmount is credited to the destination account, it defaults to Amount
+	ToAmount int64 `json:"to_amount"`
+	// ExchangeRate is the rate used to get ToAmount from Amount, it defaults to 1
+	ExchangeRate string        `json:"exchange_rate"`
+	QuoteID      uuid.NullUUID `json:"quote_id"`
+	// ReversalOf, Reason and Actor are only set on the compensating transfers written by ReverseTransferTx
+	ReversalOf  sql.NullInt64      `json:"reversal_of"`
+	Reason      sql.NullString     `json:"reason"`
+	Actor       sql.NullString     `json:"actor"`
+	Idempotency *IdempotencyParams `json:"-"`
 }
 
 type TransferTxResult struct {
@@ -60,61 +98,180 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 	err := store.execTx(ctx, func(queries *Queries) error {
 		var err error
 
-		result.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		result, err = transfer(ctx, queries, arg)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = queries.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// transfer moves money between two accounts inside the running transaction,
+// writing the transfer record and an entry for each side. A transfer between currencies is posted
+// as two legs so every currency still sums to zero: the sender pays Amount to the settlement account
+// of its currency and the settlement account of the other currency pays ToAmount to the recipient
+func transfer(ctx context.Context, queries *Queries, arg TransferTxParams) (result TransferTxResult, err error) {
+	if arg.ToAmount == 0 {
+		arg.ToAmount = arg.Amount
+	}
+	if arg.ExchangeRate == "" {
+		arg.ExchangeRate = "1"
+	}
+
+	legs, err := transferLegs(ctx, queries, arg)
+	if err != nil {
+		return
+	}
+
+	accountIDs := make([]int64, len(legs))
+	for i, leg := range legs {
+		accountIDs[i] = leg.AccountID
+	}
+	accounts, err := lockAccountsInOrder(ctx, queries, accountIDs...)
+	if err != nil {
+		return
+	}
+	fromAccount, toAccount := accounts[arg.FromAccountID], accounts[arg.ToAccountID]
+
+	err = checkNotFrozen(fromAccount, toAccount)
+	if err != nil {
+		return
+	}
+
+	err = checkAvailableFunds(fromAccount, arg.Amount)
+	if err != nil {
+		return
+	}
+
+	result.Transfer, err = queries.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.ToAmount,
+		ExchangeRate:  arg.ExchangeRate,
+		QuoteID:       arg.QuoteID,
+		ReversalOf:    arg.ReversalOf,
+		Reason:        arg.Reason,
+		Actor:         arg.Actor,
+	})
+	if err != nil {
+		var e *pq.Error
+		if errors.As(err, &e) && e.Constraint == "transfers_quote_id_key" {
+			err = ErrQuoteAlreadyUsed
+		}
+		return
+	}
+
+	for i, leg := range legs {
+		var entry Entry
+		entry, err = queries.CreateEntry(ctx, leg)
 		if err != nil {
-			return err
+			return
 		}
 
-		result.ToEntry, err = queries.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
+		var account Account
+		account, err = queries.AddAccountBalance(ctx, AddAccountBalanceParams{
+			ID:     leg.AccountID,
+			Amount: leg.Amount,
 		})
 		if err != nil {
-			return err
+			return
 		}
 
-		if arg.FromAccountID > arg.ToAccountID {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, queries, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-			if err != nil {
-				return err
-			}
-		} else {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, queries, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-			if err != nil {
-				return err
-			}
+		switch i {
+		case 0:
+			result.FromEntry, result.FromAccount = entry, account
+		case 1:
+			result.ToEntry, result.ToAccount = entry, account
 		}
+	}
+	return
+}
 
-		return nil
-	})
+// transferLegs returns the entries of a transfer, the sender and the recipient come first
+// and are followed by the settlement accounts when the transfer is between currencies
+func transferLegs(ctx context.Context, queries *Queries, arg TransferTxParams) ([]CreateEntryParams, error) {
+	legs := []CreateEntryParams{
+		{AccountID: arg.FromAccountID, Amount: -arg.Amount},
+		{AccountID: arg.ToAccountID, Amount: arg.ToAmount},
+	}
 
-	return result, err
+	fromAccount, err := queries.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	toAccount, err := queries.GetAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount.Currency == toAccount.Currency {
+		return legs, nil
+	}
+
+	fromSettlement, err := settlementAccountForCurrency(ctx, queries, fromAccount.Currency)
+	if err != nil {
+		return nil, err
+	}
+	toSettlement, err := settlementAccountForCurrency(ctx, queries, toAccount.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	legs = append(legs,
+		CreateEntryParams{AccountID: fromSettlement.ID, Amount: arg.Amount},
+		CreateEntryParams{AccountID: toSettlement.ID, Amount: -arg.ToAmount},
+	)
+	return legs, nil
 }
 
-func addMoney(ctx context.Context, q *Queries, accountID1, amount1, accountID2, amount2 int64) (account1, account2 Account, err error) {
-	account1, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID1,
-		Amount: amount1,
-	})
+// checkAvailableFunds makes sure the amount can be taken from the account without
+// dipping into money reserved by holds or going beyond the overdraft limit
+func checkAvailableFunds(account Account, amount int64) error {
+	if account.AvailableBalance-amount < -account.OverdraftLimit {
+		return fmt.Errorf("%w: account %d has available balance %d and overdraft limit %d, cannot take %d",
+			ErrInsufficientFunds, account.ID, account.AvailableBalance, account.OverdraftLimit, amount)
+	}
+	return nil
+}
+
+// checkNotFrozen makes sure none of the accounts has been frozen by an admin
+func checkNotFrozen(accounts ...Account) error {
+	for _, account := range accounts {
+		if account.IsFrozen {
+			return fmt.Errorf("%w: account %d", ErrAccountFrozen, account.ID)
+		}
+	}
+	return nil
+}
+
+// lockAccounts locks both accounts in ascending id order to avoid deadlocks, and returns the locked accounts
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID, toAccountID int64) (fromAccount, toAccount Account, err error) {
+	accounts, err := lockAccountsInOrder(ctx, q, fromAccountID, toAccountID)
 	if err != nil {
 		return
 	}
+	return accounts[fromAccountID], accounts[toAccountID], nil
+}
 
-	account2, err = q.AddAccountBalance(ctx, AddAccountBalanceParams{
-		ID:     accountID2,
-		Amount: amount2,
-	})
-	return
+// lockAccountsInOrder locks the accounts in ascending id order to avoid deadlocks, and returns them by id
+func lockAccountsInOrder(ctx context.Context, q *Queries, accountIDs ...int64) (map[int64]Account, error) {
+	ids := slices.Clone(accountIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	accounts := make(map[int64]Account, len(ids))
+	for _, id := range ids {
+		account, err := q.GetAccountForUpdate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts[id] = account
+	}
+	return accounts, nil
 }
@@ -2,16 +2,21 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hanifsyahsn/simple_bank/util"
 	"github.com/stretchr/testify/require"
 )
 
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
 
 	n := 5
 	amount := int64(10)
@@ -45,6 +50,8 @@ func TestTransferTx(t *testing.T) {
 		require.Equal(t, transfer.FromAccountID, account1.ID)
 		require.Equal(t, transfer.ToAccountID, account2.ID)
 		require.Equal(t, transfer.Amount, amount)
+		require.Equal(t, transfer.ToAmount, amount)
+		require.Equal(t, "1", transfer.ExchangeRate)
 		require.NotZero(t, transfer.ID)
 		require.NotZero(t, transfer.CreatedAt)
 
@@ -104,8 +111,8 @@ func TestTransferTx(t *testing.T) {
 func TestTransferTxDeadlock(t *testing.T) {
 	store := NewStore(testDB)
 
-	account1 := createRandomAccount(t)
-	account2 := createRandomAccount(t)
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccountWithBalance(t, 1000)
 
 	n := 10
 	amount := int64(10)
@@ -146,3 +153,652 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 10)
+	account2 := createRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        11,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := store.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestTransferTxFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 10)
+	account2 := createRandomAccount(t)
+
+	_, err := testQueries.SetAccountFrozen(context.Background(), SetAccountFrozenParams{
+		ID:       account2.ID,
+		IsFrozen: true,
+	})
+	require.NoError(t, err)
+
+	for _, arg := range []TransferTxParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 1},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 1},
+	} {
+		_, err = store.TransferTx(context.Background(), arg)
+		require.ErrorIs(t, err, ErrAccountFrozen)
+	}
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 10)
+	account2 := createRandomAccount(t)
+
+	overdraftLimit := util.RandomInt(1, 100)
+	_, err := store.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: overdraftLimit,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10 + overdraftLimit,
+	})
+	require.NoError(t, err)
+	require.Equal(t, -overdraftLimit, result.FromAccount.Balance)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestTransferTxIdempotency(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccount(t)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Idempotency: &IdempotencyParams{
+			Username:    account1.Owner,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(64),
+		},
+	}
+
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	stored, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: arg.Idempotency.Username,
+		Key:      arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.Idempotency.RequestHash, stored.RequestHash)
+
+	var storedResult TransferTxResult
+	err = json.Unmarshal(stored.ResponseBody, &storedResult)
+	require.NoError(t, err)
+	require.Equal(t, result.Transfer.ID, storedResult.Transfer.ID)
+
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+
+	updatedAccount1, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-arg.Amount, updatedAccount1.Balance)
+}
+
+func TestCreateAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	arg := CreateAccountTxParams{
+		CreateAccountParams: CreateAccountParams{
+			Owner:    user.Username,
+			Balance:  0,
+			Currency: util.RandomCurrency(),
+		},
+		Idempotency: &IdempotencyParams{
+			Username:    user.Username,
+			Key:         util.RandomString(16),
+			RequestHash: util.RandomString(64),
+		},
+	}
+
+	account, err := store.CreateAccountTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Owner, account.Owner)
+	require.Equal(t, arg.Currency, account.Currency)
+
+	stored, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: user.Username,
+		Key:      arg.Idempotency.Key,
+	})
+	require.NoError(t, err)
+
+	var storedAccount Account
+	err = json.Unmarshal(stored.ResponseBody, &storedAccount)
+	require.NoError(t, err)
+	require.Equal(t, account.ID, storedAccount.ID)
+}
+
+func TestAccountStatement(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 1000)
+	account2 := createRandomAccount(t)
+
+	amounts := []int64{10, 20, 30}
+	results := make([]TransferTxResult, len(amounts))
+	for i, amount := range amounts {
+		var err error
+		results[i], err = store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        amount,
+		})
+		require.NoError(t, err)
+	}
+
+	statement, err := store.AccountStatement(context.Background(), AccountStatementParams{
+		AccountID: account1.ID,
+		Limit:     10,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), statement.OpeningBalance)
+	require.Equal(t, int64(940), statement.ClosingBalance)
+	require.Len(t, statement.Entries, 3)
+	for i, entry := range statement.Entries {
+		require.Equal(t, results[i].FromEntry.ID, entry.ID)
+		require.Equal(t, -amounts[i], entry.Amount)
+		require.Equal(t, results[i].FromAccount.Balance, entry.RunningBalance)
+	}
+
+	statement, err = store.AccountStatement(context.Background(), AccountStatementParams{
+		AccountID: account1.ID,
+		StartTime: sql.NullTime{Time: results[1].FromEntry.CreatedAt, Valid: true},
+		Limit:     10,
+		Offset:    0,
+	})
+	require.NoError(t, err)
+	require.Equal(t, results[0].FromAccount.Balance, statement.OpeningBalance)
+	require.Equal(t, int64(940), statement.ClosingBalance)
+	require.Len(t, statement.Entries, 2)
+	require.Equal(t, results[1].FromEntry.ID, statement.Entries[0].ID)
+	require.Equal(t, results[1].FromAccount.Balance, statement.Entries[0].RunningBalance)
+}
+
+func TestTransferTxCrossCurrency(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  1000,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	account2, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+	quote := createRandomFxQuote(t, user, util.USD, util.EUR)
+
+	usdSettlement, err := store.GetSettlementAccount(context.Background(), util.USD)
+	require.NoError(t, err)
+	eurSettlement, err := store.GetSettlementAccount(context.Background(), util.EUR)
+	require.NoError(t, err)
+
+	arg := TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  quote.Rate,
+		QuoteID:       uuid.NullUUID{UUID: quote.ID, Valid: true},
+	}
+
+	result, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Amount, result.Transfer.Amount)
+	require.Equal(t, arg.ToAmount, result.Transfer.ToAmount)
+	require.Equal(t, arg.ExchangeRate, result.Transfer.ExchangeRate)
+	require.Equal(t, arg.QuoteID, result.Transfer.QuoteID)
+	require.Equal(t, -arg.Amount, result.FromEntry.Amount)
+	require.Equal(t, arg.ToAmount, result.ToEntry.Amount)
+	require.Equal(t, int64(900), result.FromAccount.Balance)
+	require.Equal(t, int64(92), result.ToAccount.Balance)
+
+	// each currency leg goes through the settlement account of its currency
+	gotUSDSettlement, err := store.GetAccount(context.Background(), usdSettlement.ID)
+	require.NoError(t, err)
+	require.Equal(t, usdSettlement.Balance+arg.Amount, gotUSDSettlement.Balance)
+
+	gotEURSettlement, err := store.GetAccount(context.Background(), eurSettlement.ID)
+	require.NoError(t, err)
+	require.Equal(t, eurSettlement.Balance-arg.ToAmount, gotEURSettlement.Balance)
+
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrQuoteAlreadyUsed)
+}
+
+func TestDepositTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccount(t)
+	settlementAccount, err := store.GetSettlementAccount(context.Background(), account.Currency)
+	require.NoError(t, err)
+	require.Equal(t, SystemUsername, settlementAccount.Owner)
+
+	arg := DepositTxParams{
+		AccountID: account.ID,
+		Amount:    util.RandomMoney(),
+	}
+
+	result, err := store.DepositTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, settlementAccount.ID, result.Transfer.FromAccountID)
+	require.Equal(t, account.ID, result.Transfer.ToAccountID)
+	require.Equal(t, arg.Amount, result.Transfer.Amount)
+	require.Equal(t, account.ID, result.Entry.AccountID)
+	require.Equal(t, arg.Amount, result.Entry.Amount)
+	require.Equal(t, account.Balance+arg.Amount, result.Account.Balance)
+
+	updatedSettlementAccount, err := store.GetAccount(context.Background(), settlementAccount.ID)
+	require.NoError(t, err)
+	require.LessOrEqual(t, updatedSettlementAccount.Balance, settlementAccount.Balance-arg.Amount)
+}
+
+func TestWithdrawTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountWithBalance(t, 100)
+
+	result, err := store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    60,
+	})
+	require.NoError(t, err)
+	require.Equal(t, account.ID, result.Transfer.FromAccountID)
+	require.Equal(t, int64(60), result.Transfer.Amount)
+	require.Equal(t, int64(-60), result.Entry.Amount)
+	require.Equal(t, int64(40), result.Account.Balance)
+
+	settlementAccount, err := store.GetAccount(context.Background(), result.Transfer.ToAccountID)
+	require.NoError(t, err)
+	require.Equal(t, SystemUsername, settlementAccount.Owner)
+	require.Equal(t, account.Currency, settlementAccount.Currency)
+
+	_, err = store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    41,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestAuthorizeHold(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+
+	hold, err := store.AuthorizeHold(context.Background(), AuthorizeHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      70,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusAuthorized, hold.Status)
+	require.Equal(t, int64(70), hold.Amount)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+	require.Equal(t, int64(30), account.AvailableBalance)
+
+	_, err = store.AuthorizeHold(context.Background(), AuthorizeHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      31,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        31,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+}
+
+func TestCaptureHold(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 0)
+
+	hold, err := store.AuthorizeHold(context.Background(), AuthorizeHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      70,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = store.CaptureHold(context.Background(), CaptureHoldParams{HoldID: hold.ID, Amount: 71})
+	require.ErrorIs(t, err, ErrCaptureExceedsHold)
+
+	result, err := store.CaptureHold(context.Background(), CaptureHoldParams{HoldID: hold.ID, Amount: 50})
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusCaptured, result.Hold.Status)
+	require.Equal(t, int64(50), result.Hold.CapturedAmount)
+	require.Equal(t, result.Transfer.Transfer.ID, result.Hold.TransferID.Int64)
+	require.Equal(t, int64(50), result.Transfer.Transfer.Amount)
+
+	// the uncaptured 20 is released back to the account
+	require.Equal(t, int64(50), result.Transfer.FromAccount.Balance)
+	require.Equal(t, int64(50), result.Transfer.FromAccount.AvailableBalance)
+	require.Equal(t, int64(50), result.Transfer.ToAccount.Balance)
+	require.Equal(t, int64(50), result.Transfer.ToAccount.AvailableBalance)
+
+	_, err = store.CaptureHold(context.Background(), CaptureHoldParams{HoldID: hold.ID})
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestVoidHold(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+
+	hold, err := store.AuthorizeHold(context.Background(), AuthorizeHoldParams{
+		AccountID:   account1.ID,
+		ToAccountID: account2.ID,
+		Amount:      70,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	hold, err = store.VoidHold(context.Background(), hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, HoldStatusVoided, hold.Status)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+	require.Equal(t, int64(100), account.AvailableBalance)
+
+	_, err = store.VoidHold(context.Background(), hold.ID)
+	require.ErrorIs(t, err, ErrHoldNotActive)
+}
+
+func TestReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 0)
+
+	original, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		ToAmount:      92,
+		ExchangeRate:  "0.92",
+	})
+	require.NoError(t, err)
+
+	arg := ReverseTransferTxParams{
+		TransferID: original.Transfer.ID,
+		Amount:     93,
+		Reason:     "damaged goods",
+		Actor:      account2.Owner,
+	}
+	_, err = store.ReverseTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRefundExceedsTransfer)
+
+	arg.Amount = 46
+	result, err := store.ReverseTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(46), result.OriginalTransfer.RefundedAmount)
+
+	reversal := result.Reversal.Transfer
+	require.Equal(t, account2.ID, reversal.FromAccountID)
+	require.Equal(t, account1.ID, reversal.ToAccountID)
+	require.Equal(t, int64(46), reversal.Amount)
+	require.Equal(t, int64(50), reversal.ToAmount)
+	require.Equal(t, original.Transfer.ID, reversal.ReversalOf.Int64)
+	require.Equal(t, arg.Reason, reversal.Reason.String)
+	require.Equal(t, arg.Actor, reversal.Actor.String)
+	require.Equal(t, int64(50), result.Reversal.ToAccount.Balance)
+	require.Equal(t, int64(46), result.Reversal.FromAccount.Balance)
+
+	// refunding the remainder gives back exactly the original amount
+	arg.Amount = 0
+	result, err = store.ReverseTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(92), result.OriginalTransfer.RefundedAmount)
+	require.Equal(t, int64(50), result.Reversal.Transfer.ToAmount)
+	require.Equal(t, int64(100), result.Reversal.ToAccount.Balance)
+	require.Zero(t, result.Reversal.FromAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	arg.TransferID = result.Reversal.Transfer.ID
+	_, err = store.ReverseTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrReversalNotReversible)
+}
+
+func TestReverseTransferTxSettlement(t *testing.T) {
+	store := NewStore(testDB)
+
+	account := createRandomAccountWithBalance(t, 100)
+	deposit, err := store.DepositTx(context.Background(), DepositTxParams{
+		AccountID: account.ID,
+		Amount:    50,
+	})
+	require.NoError(t, err)
+
+	// the recipient of a deposit cannot send it back to the settlement account
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: deposit.Transfer.ID,
+		Reason:     "undo deposit",
+		Actor:      account.Owner,
+	})
+	require.ErrorIs(t, err, ErrSettlementNotReversible)
+
+	withdrawal, err := store.WithdrawTx(context.Background(), WithdrawTxParams{
+		AccountID: account.ID,
+		Amount:    50,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ReverseTransferTx(context.Background(), ReverseTransferTxParams{
+		TransferID: withdrawal.Transfer.ID,
+		Reason:     "undo withdrawal",
+		Actor:      account.Owner,
+	})
+	require.ErrorIs(t, err, ErrSettlementNotReversible)
+
+	updated, err := store.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), updated.Balance)
+}
+
+func TestRunScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 0)
+
+	scheduled, err := store.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+		Schedule:      sql.NullString{String: "@daily", Valid: true},
+		NextRunAt:     sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	arg := RunScheduledTransferTxParams{
+		ScheduledTransferID: scheduled.ID,
+		DueAt:               scheduled.NextRunAt.Time,
+		NextRunAt:           sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true},
+		RetryAt:             time.Now().Add(time.Hour),
+		MaxAttempts:         2,
+	}
+	result, err := store.RunScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferRunSucceeded, result.Run.Status)
+	require.True(t, result.Run.TransferID.Valid)
+	require.Equal(t, ScheduledTransferStatusActive, result.ScheduledTransfer.Status)
+	require.WithinDuration(t, arg.NextRunAt.Time, result.ScheduledTransfer.NextRunAt.Time, time.Second)
+
+	// the transfer is no longer due at the time it just ran
+	_, err = store.RunScheduledTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrScheduledTransferNotDue)
+
+	// only 40 is left, so the next runs fail until the transfer is suspended
+	for attempt := int32(1); attempt <= 2; attempt++ {
+		arg.DueAt = result.ScheduledTransfer.NextRunAt.Time
+		result, err = store.RunScheduledTransferTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.Equal(t, ScheduledTransferRunFailed, result.Run.Status)
+		require.False(t, result.Run.TransferID.Valid)
+		require.Contains(t, result.Run.Error.String, ErrInsufficientFunds.Error())
+		require.Equal(t, attempt, result.ScheduledTransfer.FailedAttempts)
+	}
+	require.Equal(t, ScheduledTransferStatusSuspended, result.ScheduledTransfer.Status)
+
+	runs, err := store.ListScheduledTransferRuns(context.Background(), ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), account.Balance)
+}
+
+func TestRunScheduledTransferTxSuspended(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	// there is no settlement account for this currency, so every run would fail the same way
+	account2, err := store.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: "XTS",
+	})
+	require.NoError(t, err)
+
+	scheduled, err := store.CreateScheduledTransfer(context.Background(), CreateScheduledTransferParams{
+		Owner:         account1.Owner,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        60,
+		Schedule:      sql.NullString{String: "@daily", Valid: true},
+		NextRunAt:     sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	arg := RunScheduledTransferTxParams{
+		ScheduledTransferID: scheduled.ID,
+		DueAt:               scheduled.NextRunAt.Time,
+		NextRunAt:           sql.NullTime{Time: time.Now().Add(24 * time.Hour), Valid: true},
+		RetryAt:             time.Now().Add(time.Hour),
+		MaxAttempts:         3,
+	}
+	result, err := store.RunScheduledTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, ScheduledTransferRunFailed, result.Run.Status)
+	require.False(t, result.Run.TransferID.Valid)
+	require.Contains(t, result.Run.Error.String, "no settlement account")
+	require.Equal(t, ScheduledTransferStatusSuspended, result.ScheduledTransfer.Status)
+	require.Equal(t, int32(1), result.ScheduledTransfer.FailedAttempts)
+	require.WithinDuration(t, scheduled.NextRunAt.Time, result.ScheduledTransfer.NextRunAt.Time, time.Second)
+
+	// the suspended transfer is no longer due
+	_, err = store.RunScheduledTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrScheduledTransferNotDue)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+}
+
+func TestBatchTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 0)
+	account3 := createRandomAccountWithBalance(t, 0)
+
+	arg := BatchTransferTxParams{
+		FromAccountID: account1.ID,
+		Items: []BatchTransferItem{
+			{ToAccountID: account2.ID, Amount: 60},
+			{ToAccountID: account3.ID, Amount: 50},
+			{ToAccountID: account3.ID, Amount: 40},
+		},
+		Atomic: true,
+	}
+
+	// the second item cannot be paid, so nothing is transferred
+	_, err := store.BatchTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	account, err := store.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), account.Balance)
+
+	arg.Atomic = false
+	result, err := store.BatchTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Succeeded)
+	require.Equal(t, 1, result.Failed)
+	require.Len(t, result.Items, 3)
+
+	require.Equal(t, BatchItemSucceeded, result.Items[0].Status)
+	require.Equal(t, int64(60), result.Items[0].Transfer.Amount)
+	require.Equal(t, BatchItemFailed, result.Items[1].Status)
+	require.Nil(t, result.Items[1].Transfer)
+	require.Contains(t, result.Items[1].Error, ErrInsufficientFunds.Error())
+	require.Equal(t, BatchItemSucceeded, result.Items[2].Status)
+	require.Equal(t, account3.ID, result.Items[2].Transfer.ToAccountID)
+
+	require.Zero(t, result.FromAccount.Balance)
+
+	account, err = store.GetAccount(context.Background(), account3.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), account.Balance)
+}
@@ -7,26 +7,83 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
 )
 
+const addTransferRefundedAmount = `-- name: AddTransferRefundedAmount :one
+UPDATE transfers
+SET refunded_amount = refunded_amount + $1
+WHERE id = $2
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, quote_id, reversal_of, refunded_amount, reason, actor
+`
+
+type AddTransferRefundedAmountParams struct {
+	Amount int64 `json:"amount"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) AddTransferRefundedAmount(ctx context.Context, arg AddTransferRefundedAmountParams) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, addTransferRefundedAmount, arg.Amount, arg.ID)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.QuoteID,
+		&i.ReversalOf,
+		&i.RefundedAmount,
+		&i.Reason,
+		&i.Actor,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    exchange_rate,
+    quote_id,
+    reversal_of,
+    reason,
+    actor
 ) VALUES (
-             $1, $2, $3
-         ) RETURNING id, from_account_id, to_account_id, amount, created_at
+             $1, $2, $3, $4, $5, $6, $7, $8, $9
+         ) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, quote_id, reversal_of, refunded_amount, reason, actor
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64          `json:"from_account_id"`
+	ToAccountID   int64          `json:"to_account_id"`
+	Amount        int64          `json:"amount"`
+	ToAmount      int64          `json:"to_amount"`
+	ExchangeRate  string         `json:"exchange_rate"`
+	QuoteID       uuid.NullUUID  `json:"quote_id"`
+	ReversalOf    sql.NullInt64  `json:"reversal_of"`
+	Reason        sql.NullString `json:"reason"`
+	Actor         sql.NullString `json:"actor"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.QuoteID,
+		arg.ReversalOf,
+		arg.Reason,
+		arg.Actor,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,12 +91,19 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.QuoteID,
+		&i.ReversalOf,
+		&i.RefundedAmount,
+		&i.Reason,
+		&i.Actor,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, quote_id, reversal_of, refunded_amount, reason, actor FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -52,31 +116,78 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.QuoteID,
+		&i.ReversalOf,
+		&i.RefundedAmount,
+		&i.Reason,
+		&i.Actor,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, quote_id, reversal_of, refunded_amount, reason, actor FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRowContext(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.QuoteID,
+		&i.ReversalOf,
+		&i.RefundedAmount,
+		&i.Reason,
+		&i.Actor,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, quote_id, reversal_of, refunded_amount, reason, actor FROM transfers
 WHERE
-        from_account_id = $1 OR
-        to_account_id = $2
+    (
+        ($1::text IN ('out', 'both') AND from_account_id = $2) OR
+        ($1::text IN ('in', 'both') AND to_account_id = $2)
+    )
+    AND ($3::bigint IS NULL OR amount >= $3)
+    AND ($4::bigint IS NULL OR amount <= $4)
+    AND ($5::timestamptz IS NULL OR created_at >= $5)
+    AND ($6::timestamptz IS NULL OR created_at < $6)
 ORDER BY id
-    LIMIT $3
-OFFSET $4
+    LIMIT $7
+OFFSET $8
 `
 
 type ListTransfersParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Limit         int32 `json:"limit"`
-	Offset        int32 `json:"offset"`
+	Direction string        `json:"direction"`
+	AccountID int64         `json:"account_id"`
+	MinAmount sql.NullInt64 `json:"min_amount"`
+	MaxAmount sql.NullInt64 `json:"max_amount"`
+	StartTime sql.NullTime  `json:"start_time"`
+	EndTime   sql.NullTime  `json:"end_time"`
+	Limit     int32         `json:"limit"`
+	Offset    int32         `json:"offset"`
 }
 
 func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error) {
 	rows, err := q.db.QueryContext(ctx, listTransfers,
-		arg.FromAccountID,
-		arg.ToAccountID,
+		arg.Direction,
+		arg.AccountID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.StartTime,
+		arg.EndTime,
 		arg.Limit,
 		arg.Offset,
 	)
@@ -93,6 +204,13 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.QuoteID,
+			&i.ReversalOf,
+			&i.RefundedAmount,
+			&i.Reason,
+			&i.Actor,
 		); err != nil {
 			return nil, err
 		}
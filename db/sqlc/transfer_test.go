@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/hanifsyahsn/simple_bank/util"
@@ -11,10 +12,13 @@ import (
 )
 
 func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
+	amount := util.RandomMoney()
 	arg := CreateTransferParams{
 		FromAccountID: account1.ID,
 		ToAccountID:   account2.ID,
-		Amount:        util.RandomMoney(),
+		Amount:        amount,
+		ToAmount:      amount,
+		ExchangeRate:  "1",
 	}
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 	require.NoError(t, err)
@@ -23,6 +27,9 @@ func createRandomTransfer(t *testing.T, account1, account2 Account) Transfer {
 	require.Equal(t, transfer.FromAccountID, account1.ID)
 	require.Equal(t, transfer.ToAccountID, account2.ID)
 	require.Equal(t, transfer.Amount, arg.Amount)
+	require.Equal(t, transfer.ToAmount, arg.ToAmount)
+	require.Equal(t, transfer.ExchangeRate, arg.ExchangeRate)
+	require.False(t, transfer.QuoteID.Valid)
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
 
@@ -55,15 +62,16 @@ func TestGetTransfer(t *testing.T) {
 func TestGetListTransfers(t *testing.T) {
 	account1 := createRandomAccount(t)
 	account2 := createRandomAccount(t)
-	for i := 0; i < 10; i++ {
+	for i := 0; i < 5; i++ {
 		createRandomTransfer(t, account1, account2)
+		createRandomTransfer(t, account2, account1)
 	}
 
 	arg := ListTransfersParams{
-		FromAccountID: account1.ID,
-		ToAccountID:   account2.ID,
-		Limit:         5,
-		Offset:        5,
+		Direction: "both",
+		AccountID: account1.ID,
+		Limit:     5,
+		Offset:    5,
 	}
 
 	transfersResult, err := testQueries.ListTransfers(context.Background(), arg)
@@ -73,9 +81,75 @@ func TestGetListTransfers(t *testing.T) {
 
 	for _, transfer := range transfersResult {
 		require.NotEmpty(t, transfer)
+		require.True(t, transfer.FromAccountID == account1.ID || transfer.ToAccountID == account1.ID)
+	}
+}
+
+func TestGetListTransfersByDirection(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	for i := 0; i < 3; i++ {
+		createRandomTransfer(t, account1, account2)
+		createRandomTransfer(t, account2, account1)
+	}
+
+	arg := ListTransfersParams{
+		Direction: "out",
+		AccountID: account1.ID,
+		Limit:     10,
+		Offset:    0,
+	}
+
+	transfersResult, err := testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfersResult, 3)
+	for _, transfer := range transfersResult {
+		require.Equal(t, account1.ID, transfer.FromAccountID)
+	}
+
+	arg.Direction = "in"
+	transfersResult, err = testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Len(t, transfersResult, 3)
+	for _, transfer := range transfersResult {
+		require.Equal(t, account1.ID, transfer.ToAccountID)
 	}
 }
 
+func TestGetListTransfersWithFilters(t *testing.T) {
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	var transfers []Transfer
+	for i := 0; i < 5; i++ {
+		transfers = append(transfers, createRandomTransfer(t, account1, account2))
+	}
+
+	minAmount := transfers[0].Amount
+	arg := ListTransfersParams{
+		Direction: "both",
+		AccountID: account1.ID,
+		MinAmount: sql.NullInt64{Int64: minAmount, Valid: true},
+		MaxAmount: sql.NullInt64{Int64: minAmount, Valid: true},
+		StartTime: sql.NullTime{Time: transfers[0].CreatedAt.Add(-time.Second), Valid: true},
+		EndTime:   sql.NullTime{Time: time.Now().Add(time.Second), Valid: true},
+		Limit:     10,
+		Offset:    0,
+	}
+
+	transfersResult, err := testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, transfersResult)
+	for _, transfer := range transfersResult {
+		require.Equal(t, minAmount, transfer.Amount)
+	}
+
+	arg.EndTime = sql.NullTime{Time: transfers[0].CreatedAt.Add(-time.Second), Valid: true}
+	transfersResult, err = testQueries.ListTransfers(context.Background(), arg)
+	require.NoError(t, err)
+	require.Empty(t, transfersResult)
+}
+
 func TestGetListTransfers_Error(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -87,33 +161,36 @@ func TestGetListTransfers_Error(t *testing.T) {
 	account2 := createRandomAccount(t)
 
 	arg := ListTransfersParams{
-		FromAccountID: account1.ID,
-		ToAccountID:   account2.ID,
-		Limit:         5,
-		Offset:        5,
+		Direction: "both",
+		AccountID: account1.ID,
+		Limit:     5,
+		Offset:    5,
 	}
 
-	mock.ExpectQuery("SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers").
+	query := "SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, quote_id, reversal_of, refunded_amount, reason, actor FROM transfers"
+	columns := []string{"id", "from_account_id", "to_account_id", "amount", "created_at", "to_amount", "exchange_rate", "quote_id", "reversal_of", "refunded_amount", "reason", "actor"}
+
+	mock.ExpectQuery(query).
 		WillReturnError(fmt.Errorf("query error"))
 
 	_, err = queries.ListTransfers(context.Background(), arg)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "query error")
 
-	rows := mock.NewRows([]string{"id", "from_account_id", "to_account_id", "amount", "created_at"}).
-		AddRow("not-an-int", account1.ID, account2.ID, "not-a-number", "not-a-time")
+	rows := mock.NewRows(columns).
+		AddRow("not-an-int", account1.ID, account2.ID, "not-a-number", "not-a-time", "not-a-number", "1", nil, nil, 0, nil, nil)
 
-	mock.ExpectQuery("SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers").
+	mock.ExpectQuery(query).
 		WillReturnRows(rows)
 
 	_, err = queries.ListTransfers(context.Background(), arg)
 	require.Error(t, err)
 
-	rows = mock.NewRows([]string{"id", "from_account_id", "to_account_id", "amount", "created_at"}).
-		AddRow(1, account1.ID, account2.ID, 100, time.Now()).
+	rows = mock.NewRows(columns).
+		AddRow(1, account1.ID, account2.ID, 100, time.Now(), 100, "1", nil, nil, 0, nil, nil).
 		RowError(0, fmt.Errorf("iteration error"))
 
-	mock.ExpectQuery("SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers").
+	mock.ExpectQuery(query).
 		WillReturnRows(rows)
 
 	_, err = queries.ListTransfers(context.Background(), arg)
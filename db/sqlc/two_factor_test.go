@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollTOTPTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	codes, err := util.GenerateRecoveryCodes(3)
+	require.NoError(t, err)
+
+	arg := EnrollTOTPTxParams{
+		Username:   user.Username,
+		TotpSecret: []byte(util.RandomString(32)),
+	}
+	for _, code := range codes {
+		arg.RecoveryCodeHashes = append(arg.RecoveryCodeHashes, util.HashRecoveryCode(code))
+	}
+
+	enrolledUser, err := store.EnrollTOTPTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.TotpSecret, enrolledUser.TotpSecret)
+	require.False(t, enrolledUser.IsTotpEnabled)
+
+	enabledUser, err := testQueries.EnableUserTOTP(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.True(t, enabledUser.IsTotpEnabled)
+
+	// a recovery code can only be used once
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.HashRecoveryCode(codes[0]),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.HashRecoveryCode(codes[0]),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// enrolling again replaces the recovery codes
+	_, err = store.EnrollTOTPTx(context.Background(), EnrollTOTPTxParams{
+		Username:   user.Username,
+		TotpSecret: []byte(util.RandomString(32)),
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UseRecoveryCode(context.Background(), UseRecoveryCodeParams{
+		Username: user.Username,
+		CodeHash: util.HashRecoveryCode(codes[1]),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestEnableUserTOTPNotEnrolled(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, err := testQueries.EnableUserTOTP(context.Background(), user.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestUseTOTPStep(t *testing.T) {
+	user := createRandomUser(t)
+	require.Zero(t, user.TotpLastStep)
+
+	updatedUser, err := testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Step:     100,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(100), updatedUser.TotpLastStep)
+
+	// a code of the same step or an earlier one has already been used
+	for _, step := range []int64{100, 99} {
+		_, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+			Step:     step,
+			Username: user.Username,
+		})
+		require.ErrorIs(t, err, sql.ErrNoRows)
+	}
+
+	updatedUser, err = testQueries.UseTOTPStep(context.Background(), UseTOTPStepParams{
+		Step:     101,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(101), updatedUser.TotpLastStep)
+}
+
+func TestLoginChallenge(t *testing.T) {
+	user := createRandomUser(t)
+
+	arg := CreateLoginChallengeParams{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	challenge, err := testQueries.CreateLoginChallenge(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, challenge.ID)
+	require.Equal(t, arg.Username, challenge.Username)
+	require.Zero(t, challenge.FailedAttempts)
+	require.WithinDuration(t, arg.ExpiresAt, challenge.ExpiresAt, time.Second)
+
+	challenge, err = testQueries.AddLoginChallengeFailedAttempt(context.Background(), challenge.ID)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), challenge.FailedAttempts)
+
+	err = testQueries.DeleteLoginChallenge(context.Background(), challenge.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetLoginChallenge(context.Background(), challenge.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
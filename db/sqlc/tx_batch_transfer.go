@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	BatchItemSucceeded = "succeeded"
+	BatchItemFailed    = "failed"
+)
+
+type BatchTransferItem struct {
+	ToAccountID int64 `json:"to_account_id"`
+	Amount      int64 `json:"amount"`
+}
+
+type BatchTransferTxParams struct {
+	FromAccountID int64               `json:"from_account_id"`
+	Items         []BatchTransferItem `json:"items"`
+	// Atomic runs every item or none of them, otherwise items failing for insufficient funds are skipped
+	Atomic      bool               `json:"atomic"`
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+type BatchTransferItemResult struct {
+	ToAccountID int64     `json:"to_account_id"`
+	Amount      int64     `json:"amount"`
+	Status      string    `json:"status"`
+	Transfer    *Transfer `json:"transfer,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type BatchTransferTxResult struct {
+	FromAccount Account                   `json:"from_account"`
+	Items       []BatchTransferItemResult `json:"items"`
+	Succeeded   int                       `json:"succeeded"`
+	Failed      int                       `json:"failed"`
+}
+
+// BatchTransferTx runs many transfers out of one account in a single transaction
+func (store *SQLStore) BatchTransferTx(ctx context.Context, arg BatchTransferTxParams) (BatchTransferTxResult, error) {
+	var result BatchTransferTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		// every account of the batch is locked up front in ascending id order, so two batches
+		// sharing destinations cannot deadlock on each other
+		ids := []int64{arg.FromAccountID}
+		for _, item := range arg.Items {
+			ids = append(ids, item.ToAccountID)
+		}
+		_, err := lockAccountsInOrder(ctx, queries, ids...)
+		if err != nil {
+			return err
+		}
+
+		result.Items = make([]BatchTransferItemResult, len(arg.Items))
+		for i, item := range arg.Items {
+			itemResult := BatchTransferItemResult{
+				ToAccountID: item.ToAccountID,
+				Amount:      item.Amount,
+			}
+
+			transferResult, err := transfer(ctx, queries, TransferTxParams{
+				FromAccountID: arg.FromAccountID,
+				ToAccountID:   item.ToAccountID,
+				Amount:        item.Amount,
+			})
+			switch {
+			case err == nil:
+				itemResult.Status = BatchItemSucceeded
+				itemResult.Transfer = &transferResult.Transfer
+				result.Succeeded++
+			case (errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrAccountFrozen)) && !arg.Atomic:
+				// nothing has been written by the failed transfer, so the batch carries on
+				itemResult.Status = BatchItemFailed
+				itemResult.Error = err.Error()
+				result.Failed++
+			default:
+				return fmt.Errorf("item %d: %w", i, err)
+			}
+			result.Items[i] = itemResult
+		}
+
+		result.FromAccount, err = queries.GetAccount(ctx, arg.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
@@ -0,0 +1,28 @@
+package db
+
+import "context"
+
+type CreateAccountTxParams struct {
+	CreateAccountParams
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+func (store *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountTxParams) (Account, error) {
+	var account Account
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		account, err = queries.CreateAccount(ctx, arg.CreateAccountParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, account)
+		}
+		return nil
+	})
+
+	return account, err
+}
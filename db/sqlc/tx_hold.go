@@ -0,0 +1,170 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	HoldStatusAuthorized = "authorized"
+	HoldStatusCaptured   = "captured"
+	HoldStatusVoided     = "voided"
+	HoldStatusExpired    = "expired"
+)
+
+type AuthorizeHoldParams struct {
+	AccountID   int64     `json:"account_id"`
+	ToAccountID int64     `json:"to_account_id"`
+	Amount      int64     `json:"amount"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// AuthorizeHold reserves an amount on an account so it can be captured to another account later
+func (store *SQLStore) AuthorizeHold(ctx context.Context, arg AuthorizeHoldParams) (Hold, error) {
+	var hold Hold
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		account, err := queries.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		err = checkNotFrozen(account)
+		if err != nil {
+			return err
+		}
+
+		err = checkAvailableFunds(account, arg.Amount)
+		if err != nil {
+			return err
+		}
+
+		_, err = queries.AddAccountAvailableBalance(ctx, AddAccountAvailableBalanceParams{
+			ID:     arg.AccountID,
+			Amount: -arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		hold, err = queries.CreateHold(ctx, CreateHoldParams{
+			AccountID:   arg.AccountID,
+			ToAccountID: arg.ToAccountID,
+			Amount:      arg.Amount,
+			ExpiresAt:   arg.ExpiresAt,
+		})
+		return err
+	})
+
+	return hold, err
+}
+
+type CaptureHoldParams struct {
+	HoldID int64 `json:"hold_id"`
+	// Amount is the part of the hold to capture, the whole hold is captured when it is zero
+	Amount int64 `json:"amount"`
+}
+
+type CaptureHoldResult struct {
+	Hold     Hold             `json:"hold"`
+	Transfer TransferTxResult `json:"transfer"`
+}
+
+// CaptureHold settles a hold by transferring the captured amount, any remainder of a partial capture is released
+func (store *SQLStore) CaptureHold(ctx context.Context, arg CaptureHoldParams) (CaptureHoldResult, error) {
+	var result CaptureHoldResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		hold, err := activeHold(ctx, queries, arg.HoldID)
+		if err != nil {
+			return err
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return fmt.Errorf("%w: hold %d is for %d, cannot capture %d", ErrCaptureExceedsHold, hold.ID, hold.Amount, amount)
+		}
+
+		_, _, err = lockAccounts(ctx, queries, hold.AccountID, hold.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		_, err = queries.AddAccountAvailableBalance(ctx, AddAccountAvailableBalanceParams{
+			ID:     hold.AccountID,
+			Amount: hold.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Transfer, err = transfer(ctx, queries, TransferTxParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   hold.ToAccountID,
+			Amount:        amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Hold, err = queries.UpdateHold(ctx, UpdateHoldParams{
+			ID:             hold.ID,
+			Status:         HoldStatusCaptured,
+			CapturedAmount: amount,
+			TransferID:     sql.NullInt64{Int64: result.Transfer.Transfer.ID, Valid: true},
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// VoidHold cancels a hold and releases the reserved amount
+func (store *SQLStore) VoidHold(ctx context.Context, holdID int64) (Hold, error) {
+	var hold Hold
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		hold, err = activeHold(ctx, queries, holdID)
+		if err != nil {
+			return err
+		}
+
+		_, err = queries.AddAccountAvailableBalance(ctx, AddAccountAvailableBalanceParams{
+			ID:     hold.AccountID,
+			Amount: hold.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		hold, err = queries.UpdateHold(ctx, UpdateHoldParams{
+			ID:     hold.ID,
+			Status: HoldStatusVoided,
+		})
+		return err
+	})
+
+	return hold, err
+}
+
+// activeHold locks a hold and makes sure it can still be captured or voided
+func activeHold(ctx context.Context, q *Queries, holdID int64) (Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+	if hold.Status != HoldStatusAuthorized {
+		return hold, fmt.Errorf("%w: hold %d is %s", ErrHoldNotActive, hold.ID, hold.Status)
+	}
+	if !time.Now().Before(hold.ExpiresAt) {
+		return hold, fmt.Errorf("%w: hold %d expired at %s", ErrHoldNotActive, hold.ID, hold.ExpiresAt)
+	}
+	return hold, nil
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type UpdatePasswordTxParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+// UpdatePasswordTx sets a new password, and invalidates the outstanding reset tokens, the sessions and the API keys of the user
+func (store *SQLStore) UpdatePasswordTx(ctx context.Context, arg UpdatePasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		user, err = updatePassword(ctx, queries, arg.Username, arg.HashedPassword)
+		return err
+	})
+
+	return user, err
+}
+
+type ResetPasswordTxParams struct {
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+	// BeforeUpdate runs inside the transaction with the user the token belongs to,
+	// the password is not changed and the token stays usable when it fails
+	BeforeUpdate func(user User) error `json:"-"`
+}
+
+// ResetPasswordTx sets a new password with a reset token, each token can only be used once before it expires
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		resetToken, err := queries.GetPasswordResetTokenForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if resetToken.UsedAt.Valid || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		if arg.BeforeUpdate != nil {
+			user, err = queries.GetUser(ctx, resetToken.Username)
+			if err != nil {
+				return err
+			}
+			if err = arg.BeforeUpdate(user); err != nil {
+				return err
+			}
+		}
+
+		user, err = updatePassword(ctx, queries, resetToken.Username, arg.HashedPassword)
+		return err
+	})
+
+	return user, err
+}
+
+// updatePassword sets the password of the user inside the running transaction, using up every reset token,
+// blocking every session and revoking every API key issued with the old password
+func updatePassword(ctx context.Context, queries *Queries, username string, hashedPassword string) (User, error) {
+	user, err := queries.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		HashedPassword: hashedPassword,
+		Username:       username,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	err = queries.InvalidatePasswordResetTokens(ctx, username)
+	if err != nil {
+		return user, err
+	}
+
+	err = revokeUserCredentials(ctx, queries, username)
+	return user, err
+}
+
+// LogoutAllTx blocks every session and revokes every API key of the user
+func (store *SQLStore) LogoutAllTx(ctx context.Context, username string) error {
+	return store.execTx(ctx, func(queries *Queries) error {
+		return revokeUserCredentials(ctx, queries, username)
+	})
+}
+
+// revokeUserCredentials blocks the sessions and revokes the API keys of the user inside the running transaction,
+// nothing issued before keeps working
+func revokeUserCredentials(ctx context.Context, queries *Queries, username string) error {
+	err := queries.BlockUserSessions(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return queries.RevokeUserAPIKeys(ctx, username)
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	PendingTransferStatusPending   = "pending"
+	PendingTransferStatusConfirmed = "confirmed"
+)
+
+type CreatePendingTransferTxParams struct {
+	CreatePendingTransferParams
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+// CreatePendingTransferTx records a transfer waiting for the confirmation of its owner,
+// no money moves until ConfirmPendingTransferTx runs
+func (store *SQLStore) CreatePendingTransferTx(ctx context.Context, arg CreatePendingTransferTxParams) (PendingTransfer, error) {
+	var pending PendingTransfer
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		pending, err = queries.CreatePendingTransfer(ctx, arg.CreatePendingTransferParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, pending)
+		}
+		return nil
+	})
+
+	return pending, err
+}
+
+type ConfirmPendingTransferTxParams struct {
+	ID int64 `json:"id"`
+	// MaxFailedAttempts is how many wrong passwords or codes the pending transfer accepts before it can no longer be confirmed
+	MaxFailedAttempts int32 `json:"max_failed_attempts"`
+	// CheckCredentials checks the password or code of the owner while the pending transfer is locked,
+	// so concurrent confirmations cannot get more guesses than MaxFailedAttempts
+	CheckCredentials func(pending PendingTransfer) (bool, error) `json:"-"`
+}
+
+// ConfirmPendingTransferTx checks the credentials of the owner and executes the pending transfer, marking it confirmed.
+// A wrong password or code is counted on the pending transfer under the same lock and ErrPendingTransferInvalidCredentials
+// is returned, a pending transfer runs at most once
+func (store *SQLStore) ConfirmPendingTransferTx(ctx context.Context, arg ConfirmPendingTransferTxParams) (TransferTxResult, error) {
+	var result TransferTxResult
+	var invalidCredentials bool
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		pending, err := queries.GetPendingTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if pending.Status != PendingTransferStatusPending {
+			return fmt.Errorf("%w: pending transfer %d", ErrPendingTransferConfirmed, pending.ID)
+		}
+		if time.Now().After(pending.ExpiresAt) {
+			return fmt.Errorf("%w: pending transfer %d", ErrPendingTransferExpired, pending.ID)
+		}
+		if pending.FailedAttempts >= arg.MaxFailedAttempts {
+			return fmt.Errorf("%w: pending transfer %d", ErrPendingTransferTooManyAttempts, pending.ID)
+		}
+
+		valid, err := arg.CheckCredentials(pending)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			// the failed attempt is committed, only the transfer is skipped
+			invalidCredentials = true
+			_, err = queries.AddPendingTransferFailedAttempt(ctx, pending.ID)
+			return err
+		}
+
+		result, err = transfer(ctx, queries, TransferTxParams{
+			FromAccountID: pending.FromAccountID,
+			ToAccountID:   pending.ToAccountID,
+			Amount:        pending.Amount,
+			ToAmount:      pending.ToAmount,
+			ExchangeRate:  pending.ExchangeRate,
+			QuoteID:       pending.QuoteID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = queries.ConfirmPendingTransfer(ctx, ConfirmPendingTransferParams{
+			ID:         pending.ID,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		})
+		return err
+	})
+	if err == nil && invalidCredentials {
+		return TransferTxResult{}, ErrPendingTransferInvalidCredentials
+	}
+
+	return result, err
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	"github.com/hanifsyahsn/simple_bank/fx"
+)
+
+type ReverseTransferTxParams struct {
+	TransferID int64 `json:"transfer_id"`
+	// Amount is the part of the original to_amount to give back, the whole remainder is refunded when it is zero
+	Amount      int64              `json:"amount"`
+	Reason      string             `json:"reason"`
+	Actor       string             `json:"actor"`
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+type ReverseTransferTxResult struct {
+	OriginalTransfer Transfer         `json:"original_transfer"`
+	Reversal         TransferTxResult `json:"reversal"`
+}
+
+// ReverseTransferTx refunds a transfer, fully or partially, with a compensating transfer
+// from its destination back to its source account. Deposits and withdrawals cannot be reversed
+func (store *SQLStore) ReverseTransferTx(ctx context.Context, arg ReverseTransferTxParams) (ReverseTransferTxResult, error) {
+	var result ReverseTransferTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		original, err := queries.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+		if original.ReversalOf.Valid {
+			return fmt.Errorf("%w: transfer %d reverses transfer %d", ErrReversalNotReversible, original.ID, original.ReversalOf.Int64)
+		}
+
+		// a deposit or withdrawal moved money through a settlement account, giving it back is a banker's job
+		for _, accountID := range []int64{original.FromAccountID, original.ToAccountID} {
+			account, err := queries.GetAccount(ctx, accountID)
+			if err != nil {
+				return err
+			}
+			if account.Owner == SystemUsername {
+				return fmt.Errorf("%w: transfer %d", ErrSettlementNotReversible, original.ID)
+			}
+		}
+
+		remainder := original.ToAmount - original.RefundedAmount
+		if remainder == 0 {
+			return fmt.Errorf("%w: transfer %d", ErrTransferAlreadyReversed, original.ID)
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remainder
+		}
+		if amount > remainder {
+			return fmt.Errorf("%w: transfer %d has %d left to refund, cannot refund %d", ErrRefundExceedsTransfer, original.ID, remainder, amount)
+		}
+
+		toAmount, exchangeRate, err := refundedAmount(original, amount)
+		if err != nil {
+			return err
+		}
+		if toAmount <= 0 {
+			return fmt.Errorf("%w: refunding %d of transfer %d", ErrRefundTooSmall, amount, original.ID)
+		}
+
+		result.Reversal, err = transfer(ctx, queries, TransferTxParams{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        amount,
+			ToAmount:      toAmount,
+			ExchangeRate:  exchangeRate,
+			ReversalOf:    sql.NullInt64{Int64: original.ID, Valid: true},
+			Reason:        sql.NullString{String: arg.Reason, Valid: true},
+			Actor:         sql.NullString{String: arg.Actor, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.OriginalTransfer, err = queries.AddTransferRefundedAmount(ctx, AddTransferRefundedAmountParams{
+			ID:     original.ID,
+			Amount: amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// refundedAmount converts a refund of the original to_amount back into the source currency at the
+// rate the original transfer actually applied. The running refunded total is converted rather than
+// the refund alone, so refunding the whole transfer in parts gives back exactly the original amount.
+func refundedAmount(original Transfer, amount int64) (int64, string, error) {
+	if original.Amount == original.ToAmount {
+		return amount, "", nil
+	}
+
+	rate := big.NewRat(original.Amount, original.ToAmount)
+	total, err := fx.Convert(original.RefundedAmount+amount, rate)
+	if err != nil {
+		return 0, "", err
+	}
+	alreadyRefunded, err := fx.Convert(original.RefundedAmount, rate)
+	if err != nil {
+		return 0, "", err
+	}
+	return total - alreadyRefunded, fx.FormatRate(rate), nil
+}
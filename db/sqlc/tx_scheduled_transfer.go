@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusSuspended = "suspended"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+const (
+	ScheduledTransferRunSucceeded = "succeeded"
+	ScheduledTransferRunFailed    = "failed"
+)
+
+type RunScheduledTransferTxParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// DueAt is the next_run_at the caller found the transfer due at, the run is skipped if it has changed since
+	DueAt time.Time `json:"due_at"`
+	// NextRunAt is when a recurring transfer runs again after this run, a one-off transfer leaves it null
+	NextRunAt sql.NullTime `json:"next_run_at"`
+	// RetryAt is when a run that failed for insufficient funds is tried again
+	RetryAt time.Time `json:"retry_at"`
+	// MaxAttempts is how many runs in a row may fail for insufficient funds before the transfer is suspended
+	MaxAttempts int32 `json:"max_attempts"`
+}
+
+type RunScheduledTransferTxResult struct {
+	ScheduledTransfer ScheduledTransfer    `json:"scheduled_transfer"`
+	Run               ScheduledTransferRun `json:"run"`
+}
+
+// RunScheduledTransferTx executes a due scheduled transfer and records the outcome of the run.
+// A run failing for insufficient funds is recorded and retried later rather than returned as an error,
+// a run failing for any other reason that is not transient is recorded and the transfer is suspended.
+func (store *SQLStore) RunScheduledTransferTx(ctx context.Context, arg RunScheduledTransferTxParams) (RunScheduledTransferTxResult, error) {
+	var result RunScheduledTransferTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		scheduled, err := queries.GetScheduledTransferForUpdate(ctx, arg.ScheduledTransferID)
+		if err != nil {
+			return err
+		}
+		if scheduled.Status != ScheduledTransferStatusActive || !scheduled.NextRunAt.Valid || !scheduled.NextRunAt.Time.Equal(arg.DueAt) {
+			return fmt.Errorf("%w: scheduled transfer %d", ErrScheduledTransferNotDue, scheduled.ID)
+		}
+
+		run := CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+		}
+		state := SetScheduledTransferStateParams{
+			ID: scheduled.ID,
+		}
+
+		transferResult, err := transfer(ctx, queries, TransferTxParams{
+			FromAccountID: scheduled.FromAccountID,
+			ToAccountID:   scheduled.ToAccountID,
+			Amount:        scheduled.Amount,
+		})
+		switch {
+		case err == nil:
+			run.Status = ScheduledTransferRunSucceeded
+			run.TransferID = sql.NullInt64{Int64: transferResult.Transfer.ID, Valid: true}
+			state.NextRunAt = arg.NextRunAt
+			state.Status = ScheduledTransferStatusActive
+			if !arg.NextRunAt.Valid {
+				state.Status = ScheduledTransferStatusCompleted
+			}
+		case errors.Is(err, ErrInsufficientFunds) || errors.Is(err, ErrAccountFrozen):
+			// nothing has been written by the failed transfer, so the run is recorded in the same transaction
+			run.Status = ScheduledTransferRunFailed
+			run.Error = sql.NullString{String: err.Error(), Valid: true}
+			state.FailedAttempts = scheduled.FailedAttempts + 1
+			state.NextRunAt = sql.NullTime{Time: arg.RetryAt, Valid: true}
+			state.Status = ScheduledTransferStatusActive
+			if state.FailedAttempts >= arg.MaxAttempts {
+				state.NextRunAt = scheduled.NextRunAt
+				state.Status = ScheduledTransferStatusSuspended
+			}
+		default:
+			return err
+		}
+
+		result.Run, err = queries.CreateScheduledTransferRun(ctx, run)
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = queries.SetScheduledTransferState(ctx, state)
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrScheduledTransferNotDue) && !isTransientError(err) {
+		// the failed transfer may have left the transaction aborted, so the run is recorded in a new one
+		return store.SuspendScheduledTransferTx(ctx, SuspendScheduledTransferTxParams{
+			ScheduledTransferID: arg.ScheduledTransferID,
+			DueAt:               arg.DueAt,
+			Error:               err.Error(),
+		})
+	}
+
+	return result, err
+}
+
+type SuspendScheduledTransferTxParams struct {
+	ScheduledTransferID int64 `json:"scheduled_transfer_id"`
+	// DueAt is the next_run_at the caller found the transfer due at, nothing is done if it has changed since
+	DueAt time.Time `json:"due_at"`
+	// Error is recorded on the failed run
+	Error string `json:"error"`
+}
+
+// SuspendScheduledTransferTx records a failed run of a due scheduled transfer and suspends it,
+// for failures that would happen again on every retry
+func (store *SQLStore) SuspendScheduledTransferTx(ctx context.Context, arg SuspendScheduledTransferTxParams) (RunScheduledTransferTxResult, error) {
+	var result RunScheduledTransferTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		scheduled, err := queries.GetScheduledTransferForUpdate(ctx, arg.ScheduledTransferID)
+		if err != nil {
+			return err
+		}
+		if scheduled.Status != ScheduledTransferStatusActive || !scheduled.NextRunAt.Valid || !scheduled.NextRunAt.Time.Equal(arg.DueAt) {
+			return fmt.Errorf("%w: scheduled transfer %d", ErrScheduledTransferNotDue, scheduled.ID)
+		}
+
+		result.Run, err = queries.CreateScheduledTransferRun(ctx, CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			Status:              ScheduledTransferRunFailed,
+			Error:               sql.NullString{String: arg.Error, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer, err = queries.SetScheduledTransferState(ctx, SetScheduledTransferStateParams{
+			ID:             scheduled.ID,
+			NextRunAt:      scheduled.NextRunAt,
+			Status:         ScheduledTransferStatusSuspended,
+			FailedAttempts: scheduled.FailedAttempts + 1,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+// isTransientError reports whether err may go away by itself, such as a lost connection, a deadlock
+// or a serialization failure, in which case the run is left to be tried again on the next tick
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var e *pq.Error
+	if errors.As(err, &e) {
+		switch e.Code.Class() {
+		// connection exception, transaction rollback, insufficient resources and operator intervention
+		case "08", "40", "53", "57":
+			return true
+		}
+	}
+	return false
+}
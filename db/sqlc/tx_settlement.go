@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SystemUsername owns the per-currency settlement accounts that balance deposits and withdrawals,
+// so the balances of every currency always sum to zero
+const SystemUsername = "system"
+
+type DepositTxParams struct {
+	AccountID   int64              `json:"account_id"`
+	Amount      int64              `json:"amount"`
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+type DepositTxResult struct {
+	Transfer Transfer `json:"transfer"`
+	Account  Account  `json:"account"`
+	Entry    Entry    `json:"entry"`
+}
+
+// DepositTx brings money into an account by transferring it from the settlement account of its currency
+func (store *SQLStore) DepositTx(ctx context.Context, arg DepositTxParams) (DepositTxResult, error) {
+	var result DepositTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		settlementAccount, err := settlementAccountFor(ctx, queries, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		transferResult, err := transfer(ctx, queries, TransferTxParams{
+			FromAccountID: settlementAccount.ID,
+			ToAccountID:   arg.AccountID,
+			Amount:        arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		result.Transfer = transferResult.Transfer
+		result.Account = transferResult.ToAccount
+		result.Entry = transferResult.ToEntry
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+type WithdrawTxParams struct {
+	AccountID   int64              `json:"account_id"`
+	Amount      int64              `json:"amount"`
+	Idempotency *IdempotencyParams `json:"-"`
+}
+
+type WithdrawTxResult struct {
+	Transfer Transfer `json:"transfer"`
+	Account  Account  `json:"account"`
+	Entry    Entry    `json:"entry"`
+}
+
+// WithdrawTx takes money out of an account by transferring it to the settlement account of its currency
+func (store *SQLStore) WithdrawTx(ctx context.Context, arg WithdrawTxParams) (WithdrawTxResult, error) {
+	var result WithdrawTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		settlementAccount, err := settlementAccountFor(ctx, queries, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		transferResult, err := transfer(ctx, queries, TransferTxParams{
+			FromAccountID: arg.AccountID,
+			ToAccountID:   settlementAccount.ID,
+			Amount:        arg.Amount,
+		})
+		if err != nil {
+			return err
+		}
+		result.Transfer = transferResult.Transfer
+		result.Account = transferResult.FromAccount
+		result.Entry = transferResult.FromEntry
+
+		if arg.Idempotency != nil {
+			return saveIdempotentResponse(ctx, queries, arg.Idempotency, result)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// settlementAccountFor returns the settlement account with the same currency as the given account
+func settlementAccountFor(ctx context.Context, q *Queries, accountID int64) (Account, error) {
+	account, err := q.GetAccount(ctx, accountID)
+	if err != nil {
+		return account, err
+	}
+	if account.Owner == SystemUsername {
+		return account, fmt.Errorf("account %d is a settlement account", accountID)
+	}
+
+	return settlementAccountForCurrency(ctx, q, account.Currency)
+}
+
+// settlementAccountForCurrency returns the settlement account of the currency
+func settlementAccountForCurrency(ctx context.Context, q *Queries, currency string) (Account, error) {
+	settlementAccount, err := q.GetSettlementAccount(ctx, currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settlementAccount, fmt.Errorf("no settlement account for currency %s", currency)
+	}
+	return settlementAccount, err
+}
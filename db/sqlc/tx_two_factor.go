@@ -0,0 +1,45 @@
+package db
+
+import "context"
+
+type EnrollTOTPTxParams struct {
+	Username string `json:"username"`
+	// TotpSecret is the encrypted secret, it stays pending until a code has been verified
+	TotpSecret         []byte   `json:"totp_secret"`
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// EnrollTOTPTx stores a new pending TOTP secret for a user and replaces its recovery codes
+func (store *SQLStore) EnrollTOTPTx(ctx context.Context, arg EnrollTOTPTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		user, err = queries.SetUserTOTPSecret(ctx, SetUserTOTPSecretParams{
+			TotpSecret: arg.TotpSecret,
+			Username:   arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = queries.DeleteRecoveryCodes(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range arg.RecoveryCodeHashes {
+			_, err = queries.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: codeHash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return user, err
+}
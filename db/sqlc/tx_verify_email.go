@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	SecretCodeHash string    `json:"secret_code_hash"`
+	ExpiredAt      time.Time `json:"expired_at"`
+}
+
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates a user along with the code that verifies its email
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		var err error
+
+		result.User, err = queries.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = queries.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:       result.User.Username,
+			Email:          result.User.Email,
+			SecretCodeHash: arg.SecretCodeHash,
+			ExpiredAt:      arg.ExpiredAt,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+type VerifyEmailTxParams struct {
+	EmailID        int64  `json:"email_id"`
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+// VerifyEmailTx marks the email of a user as verified, each code can only be used once before it expires
+// and only while the user still has the address it was sent to
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(queries *Queries) error {
+		verifyEmail, err := queries.GetVerifyEmailForUpdate(ctx, arg.EmailID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidVerifyEmail
+			}
+			return err
+		}
+		if verifyEmail.IsUsed || time.Now().After(verifyEmail.ExpiredAt) ||
+			subtle.ConstantTimeCompare([]byte(verifyEmail.SecretCodeHash), []byte(arg.SecretCodeHash)) != 1 {
+			return ErrInvalidVerifyEmail
+		}
+
+		_, err = queries.UseVerifyEmail(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		user, err = queries.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: verifyEmail.Username,
+			Email:    verifyEmail.Email,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidVerifyEmail
+		}
+		return err
+	})
+
+	return user, err
+}
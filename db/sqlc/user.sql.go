@@ -15,7 +15,7 @@ INSERT INTO users (
 ) VALUES (
              $1, $2, $3, $4
          )
-    RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+    RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step
 `
 
 type CreateUserParams struct {
@@ -40,12 +40,42 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :one
+UPDATE users SET is_totp_enabled = true
+WHERE username = $1 AND totp_secret IS NOT NULL
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step
+`
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUserTOTP, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -59,6 +89,171 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step FROM users
+WHERE email = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :exec
+UPDATE users SET hashed_password = $1
+WHERE username = $2 AND hashed_password = $3
+`
+
+type RehashUserPasswordParams struct {
+	HashedPassword    string `json:"hashed_password"`
+	Username          string `json:"username"`
+	OldHashedPassword string `json:"old_hashed_password"`
+}
+
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, rehashUserPassword, arg.HashedPassword, arg.Username, arg.OldHashedPassword)
+	return err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :one
+UPDATE users SET totp_secret = $1, is_totp_enabled = false
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step
+`
+
+type SetUserTOTPSecretParams struct {
+	TotpSecret []byte `json:"totp_secret"`
+	Username   string `json:"username"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserTOTPSecret, arg.TotpSecret, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users SET hashed_password = $1, password_changed_at = now()
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string `json:"hashed_password"`
+	Username       string `json:"username"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HashedPassword, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :one
+UPDATE users SET totp_last_step = $1
+WHERE username = $2 AND totp_last_step < $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, useTOTPStep, arg.Step, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users SET is_email_verified = true
+WHERE username = $1 AND email = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, totp_secret, is_totp_enabled, is_email_verified, totp_last_step
+`
+
+type VerifyUserEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.IsTotpEnabled,
+		&i.IsEmailVerified,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
@@ -26,6 +26,7 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, util.DepositorRole, user.Role)
 
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
@@ -49,3 +50,43 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user.CreatedAt, userResult.CreatedAt, time.Second)
 	require.WithinDuration(t, user.PasswordChangedAt, userResult.PasswordChangedAt, time.Second)
 }
+
+func TestGetUserByEmail(t *testing.T) {
+	user := createRandomUser(t)
+	userResult, err := testQueries.GetUserByEmail(context.Background(), user.Email)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, userResult.Username)
+	require.Equal(t, user.Email, userResult.Email)
+}
+
+func TestRehashUserPassword(t *testing.T) {
+	user := createRandomUser(t)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	// a stale hash is left alone
+	err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		HashedPassword:    hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: util.RandomString(10),
+	})
+	require.NoError(t, err)
+
+	user1, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, user1.HashedPassword)
+
+	err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		HashedPassword:    hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	require.NoError(t, err)
+
+	user2, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, user2.HashedPassword)
+	// rehashing is not a password change
+	require.Equal(t, user.PasswordChangedAt, user2.PasswordChangedAt)
+}
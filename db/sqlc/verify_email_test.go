@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func randomCreateUserTxParams(t *testing.T, expiredAt time.Time) (CreateUserTxParams, string) {
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	secretCode, err := util.GenerateSecureToken(32)
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		SecretCodeHash: util.HashSecureToken(secretCode),
+		ExpiredAt:      expiredAt,
+	}
+	return arg, secretCode
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	arg, _ := randomCreateUserTxParams(t, time.Now().Add(time.Minute))
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+
+	require.Equal(t, arg.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.SecretCodeHash, result.VerifyEmail.SecretCodeHash)
+	require.False(t, result.VerifyEmail.IsUsed)
+	require.WithinDuration(t, arg.ExpiredAt, result.VerifyEmail.ExpiredAt, time.Second)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	arg, secretCode := randomCreateUserTxParams(t, time.Now().Add(time.Minute))
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        result.VerifyEmail.ID,
+		SecretCodeHash: util.HashSecureToken(util.RandomString(32)),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+
+	verifyArg := VerifyEmailTxParams{
+		EmailID:        result.VerifyEmail.ID,
+		SecretCodeHash: util.HashSecureToken(secretCode),
+	}
+
+	user, err := store.VerifyEmailTx(context.Background(), verifyArg)
+	require.NoError(t, err)
+	require.True(t, user.IsEmailVerified)
+
+	// a code can only be used once
+	_, err = store.VerifyEmailTx(context.Background(), verifyArg)
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+
+	arg, secretCode := randomCreateUserTxParams(t, time.Now().Add(-time.Second))
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:        result.VerifyEmail.ID,
+		SecretCodeHash: util.HashSecureToken(secretCode),
+	})
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+
+	user, err := testQueries.GetUser(context.Background(), arg.Username)
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+}
@@ -0,0 +1,51 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"sync"
+)
+
+type currencyPair struct {
+	from string
+	to   string
+}
+
+// MemoryRateProvider is a RateProvider that keeps exchange rates in process memory
+type MemoryRateProvider struct {
+	mu    sync.RWMutex
+	rates map[currencyPair]*big.Rat
+}
+
+// NewMemoryRateProvider creates a new MemoryRateProvider without any rates
+func NewMemoryRateProvider() *MemoryRateProvider {
+	return &MemoryRateProvider{
+		rates: make(map[currencyPair]*big.Rat),
+	}
+}
+
+// SetRate sets the rate of a currency pair, the opposite direction is derived from it unless set explicitly
+func (provider *MemoryRateProvider) SetRate(from, to string, rate *big.Rat) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	provider.rates[currencyPair{from: from, to: to}] = new(big.Rat).Set(rate)
+}
+
+// Rate returns how many units of the to currency one unit of the from currency is worth
+func (provider *MemoryRateProvider) Rate(_ context.Context, from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+
+	provider.mu.RLock()
+	defer provider.mu.RUnlock()
+
+	if rate, ok := provider.rates[currencyPair{from: from, to: to}]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := provider.rates[currencyPair{from: to, to: from}]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, ErrRateNotFound
+}
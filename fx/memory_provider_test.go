@@ -0,0 +1,42 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateProvider(t *testing.T) {
+	provider := NewMemoryRateProvider()
+	provider.SetRate(util.USD, util.EUR, big.NewRat(23, 25))
+
+	rate, err := provider.Rate(context.Background(), util.USD, util.EUR)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(23, 25), rate)
+
+	rate, err = provider.Rate(context.Background(), util.EUR, util.USD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(25, 23), rate)
+
+	rate, err = provider.Rate(context.Background(), util.CAD, util.CAD)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(1, 1), rate)
+
+	_, err = provider.Rate(context.Background(), util.USD, util.CAD)
+	require.ErrorIs(t, err, ErrRateNotFound)
+}
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider("rates.json")
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), util.USD, util.EUR)
+	require.NoError(t, err)
+	require.Equal(t, "0.92", FormatRate(rate))
+
+	_, err = NewStaticRateProvider("missing.json")
+	require.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/big"
+)
+
+const (
+	ProviderMemory = "memory"
+	ProviderStatic = "static"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider is an interface for looking up exchange rates between currencies
+type RateProvider interface {
+	// Rate returns how many units of the to currency one unit of the from currency is worth
+	Rate(ctx context.Context, from, to string) (*big.Rat, error)
+}
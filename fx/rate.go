@@ -0,0 +1,43 @@
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrAmountOutOfRange = errors.New("converted amount is out of range")
+
+// rateScale is the number of decimal places a rate keeps once it is formatted
+const rateScale = 10
+
+// ParseRate parses a positive decimal exchange rate such as "0.92"
+func ParseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid exchange rate %q", s)
+	}
+	if rate.Sign() <= 0 {
+		return nil, fmt.Errorf("exchange rate %q must be positive", s)
+	}
+	return rate, nil
+}
+
+// FormatRate formats an exchange rate as a decimal string without trailing zeros
+func FormatRate(rate *big.Rat) string {
+	s := rate.FloatString(rateScale)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert converts an amount with the given rate, rounding down to the smallest currency unit.
+// It fails when the converted amount does not fit in an int64
+func Convert(amount int64, rate *big.Rat) (int64, error) {
+	converted := new(big.Rat).Mul(big.NewRat(amount, 1), rate)
+	quotient := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !quotient.IsInt64() {
+		return 0, fmt.Errorf("%w: %d at rate %s", ErrAmountOutOfRange, amount, FormatRate(rate))
+	}
+	return quotient.Int64(), nil
+}
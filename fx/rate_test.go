@@ -0,0 +1,47 @@
+package fx
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("0.92")
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(23, 25), rate)
+
+	for _, s := range []string{"", "abc", "0", "-1.5"} {
+		_, err = ParseRate(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	require.Equal(t, "0.92", FormatRate(big.NewRat(23, 25)))
+	require.Equal(t, "1", FormatRate(big.NewRat(1, 1)))
+	require.Equal(t, "0.3333333333", FormatRate(big.NewRat(1, 3)))
+}
+
+func TestConvert(t *testing.T) {
+	testCases := []struct {
+		amount int64
+		rate   *big.Rat
+		want   int64
+	}{
+		{100, big.NewRat(23, 25), 92},
+		{1, big.NewRat(23, 25), 0},
+		{100, big.NewRat(1, 3), 33},
+		{100, big.NewRat(137, 100), 137},
+	}
+	for _, tc := range testCases {
+		converted, err := Convert(tc.amount, tc.rate)
+		require.NoError(t, err)
+		require.Equal(t, tc.want, converted)
+	}
+
+	_, err := Convert(math.MaxInt64, big.NewRat(137, 100))
+	require.ErrorIs(t, err, ErrAmountOutOfRange)
+}
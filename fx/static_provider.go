@@ -0,0 +1,36 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// NewStaticRateProvider creates a RateProvider from a JSON file mapping "FROM/TO" pairs to decimal rates,
+// e.g. {"USD/EUR": "0.92"}
+func NewStaticRateProvider(path string) (RateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	var rates map[string]string
+	if err = json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("cannot parse rates file: %w", err)
+	}
+
+	provider := NewMemoryRateProvider()
+	for pair, value := range rates {
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid currency pair %q", pair)
+		}
+		rate, err := ParseRate(value)
+		if err != nil {
+			return nil, err
+		}
+		provider.SetRate(from, to, rate)
+	}
+	return provider, nil
+}
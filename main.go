@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 
 	"github.com/hanifsyahsn/simple_bank/api"
 	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
 	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/hanifsyahsn/simple_bank/worker"
 	_ "github.com/lib/pq"
 )
 
@@ -20,6 +22,13 @@ func main() {
 		log.Fatal("Cannot connect to db:", err)
 	}
 	store := db.NewStore(conn)
+
+	holdExpirer := worker.NewHoldExpirer(store, config.HoldExpiryInterval)
+	go holdExpirer.Start(context.Background())
+
+	transferScheduler := worker.NewTransferScheduler(store, config.SchedulerInterval, config.SchedulerRetryInterval, config.SchedulerMaxAttempts)
+	go transferScheduler.Start(context.Background())
+
 	server, err := api.NewServer(store, config)
 	if err != nil {
 		log.Fatal("Cannot create server:", err)
@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// FileNotifier is a Notifier that appends the messages to a file as JSON lines instead of delivering them,
+// meant for local testing where the messages have to be read back
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileNotifier creates a new FileNotifier appending to the file at path, the file is created on the first message
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{
+		path: path,
+	}
+}
+
+// Send appends the message to the file
+func (notifier *FileNotifier) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	file, err := os.OpenFile(notifier.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
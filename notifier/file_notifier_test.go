@@ -0,0 +1,41 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	notifier := NewFileNotifier(path)
+
+	msg1 := Message{To: util.RandomEmail(), Subject: util.RandomString(6), Body: util.RandomString(12)}
+	msg2 := Message{To: util.RandomEmail(), Subject: util.RandomString(6), Body: "multi\nline"}
+
+	for _, msg := range []Message{msg1, msg2} {
+		err := notifier.Send(context.Background(), msg)
+		require.NoError(t, err)
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var messages []Message
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg Message
+		err = json.Unmarshal(scanner.Bytes(), &msg)
+		require.NoError(t, err)
+		messages = append(messages, msg)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []Message{msg1, msg2}, messages)
+}
@@ -0,0 +1,25 @@
+package notifier
+
+import (
+	"context"
+	"log"
+)
+
+// LogNotifier is a Notifier that writes the messages to the application log instead of delivering them,
+// meant for local development
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+// NewLogNotifier creates a new LogNotifier writing to the standard logger
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{
+		logger: log.Default(),
+	}
+}
+
+// Send writes the message to the log
+func (notifier *LogNotifier) Send(_ context.Context, msg Message) error {
+	notifier.logger.Printf("notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,40 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryNotifier is a Notifier that keeps the sent messages in process memory
+type MemoryNotifier struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewMemoryNotifier creates a new MemoryNotifier without any messages
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+// Send records the message
+func (notifier *MemoryNotifier) Send(_ context.Context, msg Message) error {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	notifier.messages = append(notifier.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent to the recipient, oldest first
+func (notifier *MemoryNotifier) Messages(to string) []Message {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	var messages []Message
+	for _, msg := range notifier.messages {
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryNotifier(t *testing.T) {
+	notifier := NewMemoryNotifier()
+
+	to := util.RandomEmail()
+	require.Empty(t, notifier.Messages(to))
+
+	msg1 := Message{To: to, Subject: util.RandomString(6), Body: util.RandomString(12)}
+	msg2 := Message{To: util.RandomEmail(), Subject: util.RandomString(6), Body: util.RandomString(12)}
+	msg3 := Message{To: to, Subject: util.RandomString(6), Body: util.RandomString(12)}
+
+	for _, msg := range []Message{msg1, msg2, msg3} {
+		err := notifier.Send(context.Background(), msg)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []Message{msg1, msg3}, notifier.Messages(to))
+	require.Equal(t, []Message{msg2}, notifier.Messages(msg2.To))
+}
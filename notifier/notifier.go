@@ -0,0 +1,23 @@
+package notifier
+
+import "context"
+
+const (
+	NotifierLog    = "log"
+	NotifierMemory = "memory"
+	NotifierFile   = "file"
+	NotifierSMTP   = "smtp"
+)
+
+// Message is a notification addressed to a user
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier is an interface for delivering notifications to users
+type Notifier interface {
+	// Send delivers the message to its recipient
+	Send(ctx context.Context, msg Message) error
+}
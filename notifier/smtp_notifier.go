@@ -0,0 +1,137 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// smtpTimeout bounds how long sending one email may take when the context has no earlier deadline
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier is a Notifier that delivers the messages by email through an SMTP server
+type SMTPNotifier struct {
+	address string
+	host    string
+	auth    smtp.Auth
+	from    string
+}
+
+// NewSMTPNotifier creates a new SMTPNotifier sending from the address through the server at host:port,
+// it authenticates with PLAIN when a username is given
+func NewSMTPNotifier(address string, username string, password string, from string) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address: %w", err)
+	}
+	if from == "" {
+		return nil, errors.New("missing sender address")
+	}
+
+	notifier := &SMTPNotifier{
+		address: address,
+		host:    host,
+		from:    from,
+	}
+	if username != "" {
+		notifier.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return notifier, nil
+}
+
+// Send delivers the message as a plain text email, it gives up once the context is done or smtpTimeout has passed
+func (notifier *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := buildEmail(notifier.from, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", notifier.address)
+	if err != nil {
+		return fmt.Errorf("cannot connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	// the deadline bounds every read and write of the conversation, and cancelling the context cuts it short
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	return notifier.sendMail(conn, msg.To, body)
+}
+
+// sendMail has the same conversation with the server as smtp.SendMail, over a connection that is already open
+func (notifier *SMTPNotifier) sendMail(conn net.Conn, to string, body []byte) error {
+	client, err := smtp.NewClient(conn, notifier.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: notifier.host}); err != nil {
+			return err
+		}
+	}
+	if notifier.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support authentication")
+		}
+		if err = client.Auth(notifier.auth); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(notifier.from); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(body); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildEmail formats the message as an RFC 5322 email, rejecting recipients and subjects
+// that would inject extra headers
+func buildEmail(from string, msg Message) ([]byte, error) {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("invalid email header")
+	}
+
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,157 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSMTPNotifier(t *testing.T) {
+	_, err := NewSMTPNotifier("smtp.example.com:587", "user", "secret", "bank@example.com")
+	require.NoError(t, err)
+
+	_, err = NewSMTPNotifier("smtp.example.com", "user", "secret", "bank@example.com")
+	require.Error(t, err)
+
+	_, err = NewSMTPNotifier("smtp.example.com:587", "user", "secret", "")
+	require.Error(t, err)
+}
+
+func TestBuildEmail(t *testing.T) {
+	email, err := buildEmail("bank@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Verify your email",
+		Body:    "line one\nline two",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "From: bank@example.com\r\n"+
+		"To: user@example.com\r\n"+
+		"Subject: Verify your email\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=\"utf-8\"\r\n"+
+		"\r\n"+
+		"line one\r\nline two", string(email))
+
+	_, err = buildEmail("bank@example.com", Message{
+		To:      "user@example.com\r\nBcc: other@example.com",
+		Subject: "Verify your email",
+	})
+	require.Error(t, err)
+
+	_, err = buildEmail("bank@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Verify\nBcc: other@example.com",
+	})
+	require.Error(t, err)
+}
+
+// serveSMTP answers one client on the listener like a minimal SMTP server, and returns the lines it received
+func serveSMTP(listener net.Listener) <-chan []string {
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+
+		reply("220 localhost ready")
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+
+			switch {
+			case inData:
+				if line == "." {
+					inData = false
+					reply("250 queued")
+				}
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				reply("354 go ahead")
+			case strings.HasPrefix(line, "QUIT"):
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		received <- lines
+	}()
+	return received
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := serveSMTP(listener)
+
+	notifier, err := NewSMTPNotifier(listener.Addr().String(), "", "", "bank@example.com")
+	require.NoError(t, err)
+
+	err = notifier.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Verify your email",
+		Body:    "hello",
+	})
+	require.NoError(t, err)
+
+	lines := <-received
+	require.Contains(t, lines, "MAIL FROM:<bank@example.com>")
+	require.Contains(t, lines, "RCPT TO:<user@example.com>")
+	require.Contains(t, lines, "Subject: Verify your email")
+	require.Contains(t, lines, "hello")
+}
+
+func TestSMTPNotifierSendContextDone(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// the server accepts the connection but never greets the client
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			_, _ = io.Copy(io.Discard, conn)
+		}
+	}()
+
+	notifier, err := NewSMTPNotifier(listener.Addr().String(), "", "", "bank@example.com")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = notifier.Send(ctx, Message{
+		To:      "user@example.com",
+		Subject: "Verify your email",
+		Body:    "hello",
+	})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
@@ -0,0 +1,23 @@
+package token
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DenylistMemory   = "memory"
+	DenylistPostgres = "postgres"
+)
+
+// Denylist is an interface for managing revoked tokens
+type Denylist interface {
+	// RevokeToken revokes a single token until it expires
+	RevokeToken(ctx context.Context, payload *Payload) error
+
+	// RevokeAllTokens revokes every token of a specific username issued before a specific time
+	RevokeAllTokens(ctx context.Context, username string, issuedBefore time.Time) error
+
+	// IsRevoked checks if the token has been revoked or not
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+}
@@ -1,61 +1,217 @@
 package token
 
 import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 const minSecretKeySize = 32
 
+// clockSkewLeeway is how far the clocks of the services sharing our tokens may drift apart
+// when checking the exp, nbf and iat claims
+const clockSkewLeeway = 30 * time.Second
+
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
-	secretKey string
+	method       jwt.SigningMethod
+	signingKey   any
+	verifyingKey any
+	// keyRing is set for the asymmetric makers, tokens name their key in the kid header
+	keyRing      *KeyRing
+	validMethods []string
+	issuer       string
+	audience     string
+}
+
+// jwtClaims are the claims of our JSON Web Tokens, the payload is mapped onto the registered claims
+// and the session onto the sid claim, which is left out for tokens without a session
+type jwtClaims struct {
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid,omitempty"`
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
 }
 
-// NewJWTMake creates a new JWTMaker
-func NewJWTMaker(secretKey string) (Maker, error) {
+// NewJWTMake creates a new JWTMaker signing with HS256
+func NewJWTMaker(secretKey string, issuer string, audience string) (Maker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("secret key must be at least %d characters", minSecretKeySize)
 	}
-	return &JWTMaker{secretKey: secretKey}, nil
+	maker := &JWTMaker{
+		method:       jwt.SigningMethodHS256,
+		signingKey:   []byte(secretKey),
+		verifyingKey: []byte(secretKey),
+		validMethods: []string{jwt.SigningMethodHS256.Alg()},
+		issuer:       issuer,
+		audience:     audience,
+	}
+	return maker, nil
+}
+
+// NewAsymmetricJWTMaker creates a new JWTMaker signing with the active key of a key ring, the algorithm follows the key:
+// RS256 for RSA, ES256 for P-256 ECDSA and EdDSA for Ed25519
+func NewAsymmetricJWTMaker(keyRing *KeyRing, issuer string, audience string) (Maker, error) {
+	if keyRing == nil {
+		return nil, fmt.Errorf("key ring must not be nil")
+	}
+	alg, err := keyAlgorithm(keyRing.activeKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	maker := &JWTMaker{
+		method:       jwt.GetSigningMethod(alg),
+		signingKey:   keyRing.activeKey,
+		keyRing:      keyRing,
+		validMethods: keyRing.algorithms(),
+		issuer:       issuer,
+		audience:     audience,
+	}
+	return maker, nil
+}
+
+// verifyingKeyFor returns the key verifying the token, the one named in its kid header for the asymmetric makers
+func (maker *JWTMaker) verifyingKeyFor(token *jwt.Token) (interface{}, error) {
+	if maker.keyRing == nil {
+		return maker.verifyingKey, nil
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	key, ok := maker.keyRing.PublicKey(keyID)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+	alg, err := keyAlgorithm(key)
+	if err != nil || alg != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", keyID, token.Method.Alg())
+	}
+	return key, nil
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 private key from a file
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateSessionToken(username, role, uuid.Nil, TokenTypeAccess, duration)
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// CreateSessionToken creates a new token for a specific username, role and duration, bound to a session
+func (maker *JWTMaker) CreateSessionToken(username string, role string, sessionID uuid.UUID, tokenType string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
+	payload.SessionID = sessionID
+	payload.TokenType = tokenType
+	payload.Issuer = maker.issuer
+	payload.Audience = maker.audience
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	claims := jwtClaims{
+		Username:  payload.Username,
+		Role:      payload.Role,
+		TokenType: payload.TokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        payload.ID.String(),
+			Issuer:    payload.Issuer,
+			Subject:   payload.Username,
+			Audience:  jwt.ClaimStrings{payload.Audience},
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			NotBefore: jwt.NewNumericDate(payload.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiresAt),
+		},
+	}
+
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
+	}
+
+	jwtToken := jwt.NewWithClaims(maker.method, claims)
+	if maker.keyRing != nil {
+		jwtToken.Header["kid"] = maker.keyRing.activeKeyID
+	}
+	token, err := jwtToken.SignedString(maker.signingKey)
+	return token, payload, err
 }
 
-// VerifyToken checks if the token is valid or not
+// VerifyToken checks if the token is valid or not, only the algorithms of the keys of the maker are accepted
+// and an asymmetric token must be signed with the algorithm of the key named in its kid header
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, ErrInvalidToken
-		}
-		return []byte(maker.secretKey), nil
-	})
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(maker.validMethods),
+		jwt.WithLeeway(clockSkewLeeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	claims := &jwtClaims{}
+	_, err := parser.ParseWithClaims(token, claims, maker.verifyingKeyFor)
 	if err != nil {
-		var verr *jwt.ValidationError
-		ok := errors.As(err, &verr)
-		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
+		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
 		}
 		return nil, ErrInvalidToken
 	}
 
-	payload, ok := jwtToken.Claims.(*Payload)
-	if !ok {
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil || claims.IssuedAt == nil || len(claims.Audience) != 1 {
 		return nil, ErrInvalidToken
 	}
 
+	var sessionID uuid.UUID
+	if claims.SessionID != "" {
+		sessionID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	payload := &Payload{
+		ID:        tokenID,
+		Username:  claims.Username,
+		Role:      claims.Role,
+		SessionID: sessionID,
+		TokenType: claims.TokenType,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience[0],
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+
+	err = payload.ValidFor(maker.issuer, maker.audience)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
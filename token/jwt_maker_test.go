@@ -1,65 +1,290 @@
 package token
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/hanifsyahsn/simple_bank/util"
 	"github.com/stretchr/testify/require"
 )
 
 func TestJWTMaker(t *testing.T) {
-	maker, err := NewJWTMaker(util.RandomString(32))
+	maker, err := NewJWTMaker(util.RandomString(32), testIssuer, testAudience)
 	require.NoError(t, err)
 
 	username := util.RandomOwner()
+	role := util.AdminRole
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, testIssuer, payload.Issuer)
+	require.Equal(t, testAudience, payload.Audience)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiresAt, time.Second)
+
+	requireSessionToken(t, maker)
+}
+
+func TestAsymmetricJWTMaker(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for alg, key := range map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecdsaKey, "EdDSA": ed25519Key} {
+		t.Run(alg, func(t *testing.T) {
+			keyRing, err := NewKeyRing("key-1", key, nil)
+			require.NoError(t, err)
+
+			maker, err := NewAsymmetricJWTMaker(keyRing, testIssuer, testAudience)
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			token, _, err := maker.CreateToken(username, util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwtClaims{})
+			require.NoError(t, err)
+			require.Equal(t, alg, parsed.Method.Alg())
+			require.Equal(t, "key-1", parsed.Header["kid"])
+
+			payload, err := maker.VerifyToken(token)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+		})
+	}
+
+	smallRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	_, err = NewKeyRing("key-1", smallRSAKey, nil)
+	require.Error(t, err)
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, err = NewKeyRing("key-1", p384Key, nil)
+	require.Error(t, err)
+
+	_, err = NewAsymmetricJWTMaker(nil, testIssuer, testAudience)
+	require.Error(t, err)
+}
+
+func TestAsymmetricJWTMakerKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldKeyRing, err := NewKeyRing("key-1", oldKey, nil)
+	require.NoError(t, err)
+	oldMaker, err := NewAsymmetricJWTMaker(oldKeyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKeyRing, err := NewKeyRing("key-2", newKey, map[string]crypto.PublicKey{"key-1": oldKey.Public()})
+	require.NoError(t, err)
+	newMaker, err := NewAsymmetricJWTMaker(newKeyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	// tokens signed with the retired key keep working until they expire
+	_, err = newMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, _, err := newMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	_, err = newMaker.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	_, err = oldMaker.VerifyToken(newToken)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	claims := &jwtClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(oldToken, claims)
+	require.NoError(t, err)
+
+	signWithKeyID := func(keyID any) string {
+		jwtToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		if keyID != nil {
+			jwtToken.Header["kid"] = keyID
+		}
+		token, err := jwtToken.SignedString(oldKey)
+		require.NoError(t, err)
+		return token
+	}
+
+	// a token naming an unknown key, no key, or a key of another algorithm is rejected
+	for _, keyID := range []any{"key-3", nil, 1, "key-2"} {
+		payload, err := newMaker.VerifyToken(signWithKeyID(keyID))
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "token.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	key, err := LoadPrivateKey(path)
+	require.NoError(t, err)
+	require.Equal(t, privateKey, key)
+
+	err = os.WriteFile(path, []byte("not a key"), 0600)
+	require.NoError(t, err)
+
+	_, err = LoadPrivateKey(path)
+	require.Error(t, err)
 }
 
 func TestExpiredJWTToken(t *testing.T) {
-	maker, err := NewJWTMaker(util.RandomString(32))
+	maker, err := NewJWTMaker(util.RandomString(32), testIssuer, testAudience)
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomOwner(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
 
+func TestJWTTokenClockSkew(t *testing.T) {
+	secretKey := util.RandomString(32)
+	maker, err := NewJWTMaker(secretKey, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	signToken := func(notBefore, expiresAt time.Time) string {
+		claims := jwtClaims{
+			Username: util.RandomOwner(),
+			Role:     util.DepositorRole,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        "4a5c5c5e-8d0c-4d1b-9b35-4d4a0e0b8f21",
+				Issuer:    testIssuer,
+				Audience:  jwt.ClaimStrings{testAudience},
+				IssuedAt:  jwt.NewNumericDate(notBefore),
+				NotBefore: jwt.NewNumericDate(notBefore),
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secretKey))
+		require.NoError(t, err)
+		return token
+	}
+
+	// tokens issued by a clock slightly ahead, or that expired a moment ago, are within the leeway
+	_, err = maker.VerifyToken(signToken(time.Now().Add(10*time.Second), time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(signToken(time.Now().Add(-time.Minute), time.Now().Add(-10*time.Second)))
+	require.NoError(t, err)
+
+	// beyond the leeway they are rejected
+	_, err = maker.VerifyToken(signToken(time.Now().Add(time.Minute), time.Now().Add(2*time.Minute)))
+	require.EqualError(t, err, ErrInvalidToken.Error())
+
+	_, err = maker.VerifyToken(signToken(time.Now().Add(-2*time.Minute), time.Now().Add(-time.Minute)))
+	require.EqualError(t, err, ErrExpiredToken.Error())
+}
+
 func TestInvalidJWTTokenAlgNone(t *testing.T) {
-	payload, err := NewPayload(util.RandomOwner(), time.Minute)
+	maker, err := NewJWTMaker(util.RandomString(32), testIssuer, testAudience)
 	require.NoError(t, err)
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
-	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
 	require.NoError(t, err)
 
-	maker, err := NewJWTMaker(util.RandomString(32))
+	claims := &jwtClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
 	require.NoError(t, err)
 
-	payload, err = maker.VerifyToken(token)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	token, err = jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestInvalidJWTTokenAlgConfusion(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyRing, err := NewKeyRing("key-1", privateKey, nil)
+	require.NoError(t, err)
+
+	maker, err := NewAsymmetricJWTMaker(keyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	claims := &jwtClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+
+	// an HS256 token keyed with the public key must not pass as an EdDSA one
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "key-1"
+	token, err = forged.SignedString([]byte(publicKey))
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestJWTTokenFromOtherIssuer(t *testing.T) {
+	secretKey := util.RandomString(32)
+	maker, err := NewJWTMaker(secretKey, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	otherMaker, err := NewJWTMaker(secretKey, "other_issuer", testAudience)
+	require.NoError(t, err)
+
+	token, _, err := otherMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidIssuer.Error())
+	require.Nil(t, payload)
+}
@@ -0,0 +1,233 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// minRSAKeySize is the smallest RSA modulus accepted for RS256, in bits
+const minRSAKeySize = 2048
+
+// KeyRing holds the keys of the asymmetric token makers. Tokens are signed with the active key,
+// and every key of the ring, including the retired ones, keeps verifying the tokens it signed
+type KeyRing struct {
+	activeKeyID string
+	activeKey   crypto.Signer
+	publicKeys  map[string]crypto.PublicKey
+}
+
+// NewKeyRing creates a key ring signing with the active key and verifying with the active and retired keys,
+// the keys may be RSA of at least 2048 bits, P-256 ECDSA or Ed25519
+func NewKeyRing(activeKeyID string, activeKey crypto.Signer, retiredKeys map[string]crypto.PublicKey) (*KeyRing, error) {
+	if activeKeyID == "" {
+		return nil, fmt.Errorf("active key id must not be empty")
+	}
+	if activeKey == nil {
+		return nil, fmt.Errorf("active key must not be nil")
+	}
+	if key, ok := activeKey.(ed25519.PrivateKey); ok && len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: must be exactly %d bytes", ed25519.PrivateKeySize)
+	}
+	if _, err := keyAlgorithm(activeKey.Public()); err != nil {
+		return nil, err
+	}
+
+	ring := &KeyRing{
+		activeKeyID: activeKeyID,
+		activeKey:   activeKey,
+		publicKeys:  map[string]crypto.PublicKey{activeKeyID: activeKey.Public()},
+	}
+	for keyID, key := range retiredKeys {
+		if _, ok := ring.publicKeys[keyID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", keyID)
+		}
+		if _, err := keyAlgorithm(key); err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", keyID, err)
+		}
+		ring.publicKeys[keyID] = key
+	}
+	return ring, nil
+}
+
+// ParseKeyRing creates an Ed25519 key ring from configuration: the active key is a base64 encoded 32 byte seed,
+// and the retired keys a comma separated list of keyID:base64 encoded public key
+func ParseKeyRing(activeKeyID string, activeKeySeed string, retiredKeys string) (*KeyRing, error) {
+	seed, err := base64.StdEncoding.DecodeString(activeKeySeed)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode active key: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid seed size: must be exactly %d bytes", ed25519.SeedSize)
+	}
+
+	publicKeys, err := parseRetiredKeys(retiredKeys, func(der []byte) (crypto.PublicKey, error) {
+		return ed25519.PublicKey(der), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(activeKeyID, ed25519.NewKeyFromSeed(seed), publicKeys)
+}
+
+// LoadKeyRing creates a key ring from configuration: the active key is read from a PEM encoded PKCS #8
+// private key file, and the retired keys are a comma separated list of keyID:base64 encoded PKIX public key
+func LoadKeyRing(activeKeyID string, activeKeyFile string, retiredKeys string) (*KeyRing, error) {
+	activeKey, err := LoadPrivateKey(activeKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeys, err := parseRetiredKeys(retiredKeys, func(der []byte) (crypto.PublicKey, error) {
+		return x509.ParsePKIXPublicKey(der)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKeyRing(activeKeyID, activeKey, publicKeys)
+}
+
+// parseRetiredKeys parses a comma separated list of keyID:base64 encoded public key
+func parseRetiredKeys(retiredKeys string, parseKey func(der []byte) (crypto.PublicKey, error)) (map[string]crypto.PublicKey, error) {
+	publicKeys := make(map[string]crypto.PublicKey)
+	for _, entry := range strings.Split(retiredKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		keyID, encodedKey, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("invalid retired key %q: must be keyID:base64 public key", entry)
+		}
+		if _, ok := publicKeys[keyID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", keyID)
+		}
+		der, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode retired key %q: %w", keyID, err)
+		}
+		key, err := parseKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse retired key %q: %w", keyID, err)
+		}
+		publicKeys[keyID] = key
+	}
+	return publicKeys, nil
+}
+
+// keyAlgorithm returns the JWS algorithm the key signs with: RS256 for RSA, ES256 for P-256 ECDSA and EdDSA for Ed25519
+func keyAlgorithm(key crypto.PublicKey) (string, error) {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeySize {
+			return "", fmt.Errorf("RSA key must be at least %d bits", minRSAKeySize)
+		}
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported ECDSA curve %s: must be P-256", key.Curve.Params().Name)
+		}
+		return "ES256", nil
+	case ed25519.PublicKey:
+		if len(key) != ed25519.PublicKeySize {
+			return "", fmt.Errorf("invalid public key size: must be exactly %d bytes", ed25519.PublicKeySize)
+		}
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// PublicKey returns the public key with the given id
+func (ring *KeyRing) PublicKey(keyID string) (crypto.PublicKey, bool) {
+	key, ok := ring.publicKeys[keyID]
+	return key, ok
+}
+
+// algorithms returns the JWS algorithms of the keys of the ring
+func (ring *KeyRing) algorithms() []string {
+	var algs []string
+	for _, key := range ring.publicKeys {
+		alg, _ := keyAlgorithm(key)
+		if !slices.Contains(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// JWKS returns every public key of the ring as a JSON Web Key Set, the active key first
+func (ring *KeyRing) JWKS() JSONWebKeySet {
+	keyIDs := make([]string, 0, len(ring.publicKeys))
+	for keyID := range ring.publicKeys {
+		if keyID != ring.activeKeyID {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	sort.Strings(keyIDs)
+	keyIDs = append([]string{ring.activeKeyID}, keyIDs...)
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keyIDs))}
+	for _, keyID := range keyIDs {
+		set.Keys = append(set.Keys, newJSONWebKey(keyID, ring.publicKeys[keyID]))
+	}
+	return set
+}
+
+// newJSONWebKey formats a public key of the ring as a JSON Web Key
+func newJSONWebKey(keyID string, key crypto.PublicKey) JSONWebKey {
+	alg, _ := keyAlgorithm(key)
+	jwk := JSONWebKey{
+		KeyID: keyID,
+		Use:   "sig",
+		Alg:   alg,
+	}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	}
+	return jwk
+}
+
+// JSONWebKey is a public key in the JSON Web Key format of RFC 7517, with the Ed25519 keys of RFC 8037
+type JSONWebKey struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+}
+
+// JSONWebKeySet is the document published so other services can verify our tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
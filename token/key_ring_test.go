@@ -0,0 +1,129 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyRing(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	activeKey := ed25519.NewKeyFromSeed(seed)
+
+	retiredKey, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	encodedSeed := base64.StdEncoding.EncodeToString(seed)
+	retiredKeys := fmt.Sprintf("key-1:%s", base64.StdEncoding.EncodeToString(retiredKey))
+
+	keyRing, err := ParseKeyRing("key-2", encodedSeed, retiredKeys)
+	require.NoError(t, err)
+
+	key, ok := keyRing.PublicKey("key-2")
+	require.True(t, ok)
+	require.Equal(t, activeKey.Public(), key)
+
+	key, ok = keyRing.PublicKey("key-1")
+	require.True(t, ok)
+	require.Equal(t, retiredKey, key)
+
+	_, ok = keyRing.PublicKey("key-3")
+	require.False(t, ok)
+
+	jwks := keyRing.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, "key-2", jwks.Keys[0].KeyID)
+	require.Equal(t, "key-1", jwks.Keys[1].KeyID)
+	for _, jwk := range jwks.Keys {
+		require.Equal(t, "OKP", jwk.KeyType)
+		require.Equal(t, "Ed25519", jwk.Curve)
+		require.Equal(t, "EdDSA", jwk.Alg)
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		require.NoError(t, err)
+		key, _ := keyRing.PublicKey(jwk.KeyID)
+		require.Equal(t, []byte(key.(ed25519.PublicKey)), x)
+	}
+
+	_, err = ParseKeyRing("", encodedSeed, "")
+	require.Error(t, err)
+
+	_, err = ParseKeyRing("key-2", "not base64", "")
+	require.Error(t, err)
+
+	_, err = ParseKeyRing("key-2", base64.StdEncoding.EncodeToString(seed[:16]), "")
+	require.Error(t, err)
+
+	_, err = ParseKeyRing("key-2", encodedSeed, "key-1")
+	require.Error(t, err)
+
+	_, err = ParseKeyRing("key-2", encodedSeed, "key-1:"+base64.StdEncoding.EncodeToString(seed[:16]))
+	require.Error(t, err)
+
+	_, err = ParseKeyRing("key-2", encodedSeed, "key-2:"+base64.StdEncoding.EncodeToString(retiredKey))
+	require.Error(t, err)
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	activeKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(activeKey)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "token.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	retiredKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	retiredDER, err := x509.MarshalPKIXPublicKey(retiredKey.Public())
+	require.NoError(t, err)
+	retiredKeys := fmt.Sprintf("key-1:%s", base64.StdEncoding.EncodeToString(retiredDER))
+
+	keyRing, err := LoadKeyRing("key-2", path, retiredKeys)
+	require.NoError(t, err)
+
+	jwks := keyRing.JWKS()
+	require.Len(t, jwks.Keys, 2)
+
+	rsaJWK := jwks.Keys[0]
+	require.Equal(t, "key-2", rsaJWK.KeyID)
+	require.Equal(t, "RSA", rsaJWK.KeyType)
+	require.Equal(t, "RS256", rsaJWK.Alg)
+	n, err := base64.RawURLEncoding.DecodeString(rsaJWK.N)
+	require.NoError(t, err)
+	require.Equal(t, activeKey.N.Bytes(), n)
+	require.Equal(t, "AQAB", rsaJWK.E)
+
+	ecJWK := jwks.Keys[1]
+	require.Equal(t, "key-1", ecJWK.KeyID)
+	require.Equal(t, "EC", ecJWK.KeyType)
+	require.Equal(t, "P-256", ecJWK.Curve)
+	require.Equal(t, "ES256", ecJWK.Alg)
+	x, err := base64.RawURLEncoding.DecodeString(ecJWK.X)
+	require.NoError(t, err)
+	require.Equal(t, retiredKey.X.FillBytes(make([]byte, 32)), x)
+	y, err := base64.RawURLEncoding.DecodeString(ecJWK.Y)
+	require.NoError(t, err)
+	require.Equal(t, retiredKey.Y.FillBytes(make([]byte, 32)), y)
+
+	_, err = LoadKeyRing("key-2", filepath.Join(t.TempDir(), "missing.pem"), "")
+	require.Error(t, err)
+
+	_, err = LoadKeyRing("key-2", path, "key-1:"+base64.StdEncoding.EncodeToString([]byte("not a key")))
+	require.Error(t, err)
+}
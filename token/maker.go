@@ -1,11 +1,25 @@
 package token
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TypePaseto       = "paseto"
+	TypePasetoPublic = "paseto_public"
+	TypeJWT          = "jwt"
+	TypeJWTPublic    = "jwt_public"
+)
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new access token for a specific username, role and duration, and returns its payload
+	CreateToken(username string, role string, duration time.Duration) (string, *Payload, error)
+
+	// CreateSessionToken creates a new token of the type like CreateToken, bound to the server-side session with the id
+	CreateSessionToken(username string, role string, sessionID uuid.UUID, tokenType string, duration time.Duration) (string, *Payload, error)
 
 	// VerifyToken checks if the token is valid or not
 	VerifyToken(token string) (*Payload, error)
@@ -0,0 +1,64 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryDenylist is a Denylist that keeps revoked tokens in process memory
+type MemoryDenylist struct {
+	mu     sync.RWMutex
+	tokens map[uuid.UUID]time.Time
+	users  map[string]time.Time
+}
+
+// NewMemoryDenylist creates a new MemoryDenylist
+func NewMemoryDenylist() Denylist {
+	return &MemoryDenylist{
+		tokens: make(map[uuid.UUID]time.Time),
+		users:  make(map[string]time.Time),
+	}
+}
+
+// RevokeToken revokes a single token until it expires
+func (denylist *MemoryDenylist) RevokeToken(_ context.Context, payload *Payload) error {
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+
+	now := time.Now()
+	for id, expiresAt := range denylist.tokens {
+		if now.After(expiresAt) {
+			delete(denylist.tokens, id)
+		}
+	}
+
+	denylist.tokens[payload.ID] = payload.ExpiresAt
+	return nil
+}
+
+// RevokeAllTokens revokes every token of a specific username issued before a specific time
+func (denylist *MemoryDenylist) RevokeAllTokens(_ context.Context, username string, issuedBefore time.Time) error {
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+
+	if issuedBefore.After(denylist.users[username]) {
+		denylist.users[username] = issuedBefore
+	}
+	return nil
+}
+
+// IsRevoked checks if the token has been revoked or not
+func (denylist *MemoryDenylist) IsRevoked(_ context.Context, payload *Payload) (bool, error) {
+	denylist.mu.RLock()
+	defer denylist.mu.RUnlock()
+
+	if _, ok := denylist.tokens[payload.ID]; ok {
+		return true, nil
+	}
+
+	revokedBefore, ok := denylist.users[payload.Username]
+	return ok && payload.IssuedAt.Before(revokedBefore), nil
+}
@@ -0,0 +1,82 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDenylistRevokeToken(t *testing.T) {
+	denylist := NewMemoryDenylist()
+
+	payload1, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload2, err := NewPayload(payload1.Username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	err = denylist.RevokeToken(context.Background(), payload1)
+	require.NoError(t, err)
+
+	revoked, err := denylist.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = denylist.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryDenylistRevokeAllTokens(t *testing.T) {
+	denylist := NewMemoryDenylist()
+
+	username := util.RandomOwner()
+	oldPayload, err := NewPayload(username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	otherPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	err = denylist.RevokeAllTokens(context.Background(), username, time.Now())
+	require.NoError(t, err)
+
+	newPayload, err := NewPayload(username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := denylist.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = denylist.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = denylist.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	err = denylist.RevokeAllTokens(context.Background(), username, oldPayload.IssuedAt.Add(-time.Minute))
+	require.NoError(t, err)
+
+	revoked, err = denylist.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestMemoryDenylistPrunesExpiredTokens(t *testing.T) {
+	denylist := NewMemoryDenylist().(*MemoryDenylist)
+
+	expiredPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	err = denylist.RevokeToken(context.Background(), expiredPayload)
+	require.NoError(t, err)
+	err = denylist.RevokeToken(context.Background(), payload)
+	require.NoError(t, err)
+
+	require.Len(t, denylist.tokens, 1)
+	require.Contains(t, denylist.tokens, payload.ID)
+}
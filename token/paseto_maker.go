@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/o1egl/paseto"
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -11,26 +12,39 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	issuer       string
+	audience     string
 }
 
-func NewPasetoMaker(symmetricKey string) (Maker, error) {
-	if len(symmetricKey) < chacha20poly1305.KeySize {
+func NewPasetoMaker(symmetricKey string, issuer string, audience string) (Maker, error) {
+	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
 	}
 	maker := &PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(symmetricKey),
+		issuer:       issuer,
+		audience:     audience,
 	}
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateSessionToken(username, role, uuid.Nil, TokenTypeAccess, duration)
+}
+
+func (maker *PasetoMaker) CreateSessionToken(username string, role string, sessionID uuid.UUID, tokenType string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
+	payload.SessionID = sessionID
+	payload.TokenType = tokenType
+	payload.Issuer = maker.issuer
+	payload.Audience = maker.audience
 
-	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	token, err := maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+	return token, payload, err
 }
 
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
@@ -41,7 +55,12 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = payload.checkExpiry()
+	if err != nil {
+		return nil, err
+	}
+
+	err = payload.ValidFor(maker.issuer, maker.audience)
 	if err != nil {
 		return nil, err
 	}
@@ -9,39 +9,72 @@ import (
 )
 
 func TestPasetoMaker(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
+	maker, err := NewPasetoMaker(util.RandomString(32), testIssuer, testAudience)
 	require.NoError(t, err)
 
 	username := util.RandomOwner()
+	role := util.AdminRole
 	duration := time.Minute
 
 	issuedAt := time.Now()
 	expiredAt := time.Now().Add(duration)
 
-	token, err := maker.CreateToken(username, duration)
+	token, payload, err := maker.CreateToken(username, role, duration)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.NoError(t, err)
 	require.NotEmpty(t, payload)
 
 	require.NotZero(t, payload.ID)
 	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.Equal(t, testIssuer, payload.Issuer)
+	require.Equal(t, testAudience, payload.Audience)
 	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
 	require.WithinDuration(t, expiredAt, payload.ExpiresAt, time.Second)
+
+	requireSessionToken(t, maker)
 }
 
 func TestExpiredPasetoToken(t *testing.T) {
-	maker, err := NewPasetoMaker(util.RandomString(32))
+	maker, err := NewPasetoMaker(util.RandomString(32), testIssuer, testAudience)
 	require.NoError(t, err)
 
-	token, err := maker.CreateToken(util.RandomOwner(), -time.Minute)
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
 	require.NoError(t, err)
 	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
 
-	payload, err := maker.VerifyToken(token)
+	payload, err = maker.VerifyToken(token)
 	require.Error(t, err)
 	require.EqualError(t, err, ErrExpiredToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestPasetoTokenForOtherAudience(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+	maker, err := NewPasetoMaker(symmetricKey, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	// another service sharing the key but expecting its own audience must reject the token
+	otherMaker, err := NewPasetoMaker(symmetricKey, testIssuer, "other_audience")
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := otherMaker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidAudience.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoKeySize(t *testing.T) {
+	for _, size := range []int{31, 33} {
+		maker, err := NewPasetoMaker(util.RandomString(size), testIssuer, testAudience)
+		require.Error(t, err)
+		require.Nil(t, maker)
+	}
+}
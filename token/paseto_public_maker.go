@@ -0,0 +1,98 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker is a PASETO v2.public token maker, tokens are signed with the active Ed25519 key
+// of a key ring so other services only need the public keys to verify them
+type PasetoPublicMaker struct {
+	paseto   *paseto.V2
+	keyRing  *KeyRing
+	issuer   string
+	audience string
+}
+
+// keyFooter is the unencrypted footer naming the key that signed the token
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// NewPasetoPublicMaker creates a new PasetoPublicMaker
+func NewPasetoPublicMaker(keyRing *KeyRing, issuer string, audience string) (Maker, error) {
+	if keyRing == nil {
+		return nil, fmt.Errorf("key ring must not be nil")
+	}
+	if _, ok := keyRing.activeKey.(ed25519.PrivateKey); !ok {
+		return nil, fmt.Errorf("active key must be an Ed25519 key for PASETO v2.public")
+	}
+	maker := &PasetoPublicMaker{
+		paseto:   paseto.NewV2(),
+		keyRing:  keyRing,
+		issuer:   issuer,
+		audience: audience,
+	}
+	return maker, nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	return maker.CreateSessionToken(username, role, uuid.Nil, TokenTypeAccess, duration)
+}
+
+// CreateSessionToken creates a new token for a specific username, role and duration, bound to a session
+func (maker *PasetoPublicMaker) CreateSessionToken(username string, role string, sessionID uuid.UUID, tokenType string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+	payload.SessionID = sessionID
+	payload.TokenType = tokenType
+	payload.Issuer = maker.issuer
+	payload.Audience = maker.audience
+
+	footer := keyFooter{KeyID: maker.keyRing.activeKeyID}
+	token, err := maker.paseto.Sign(maker.keyRing.activeKey, payload, footer)
+	return token, payload, err
+}
+
+// VerifyToken checks the signature of the token with the key named in its footer
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	var footer keyFooter
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := maker.keyRing.PublicKey(footer.KeyID)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	err = maker.paseto.Verify(token, publicKey, payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.checkExpiry()
+	if err != nil {
+		return nil, err
+	}
+
+	err = payload.ValidFor(maker.issuer, maker.audience)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,136 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyRing(t *testing.T, activeKeyID string, retiredKeys map[string]crypto.PublicKey) (*KeyRing, ed25519.PublicKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	keyRing, err := NewKeyRing(activeKeyID, privateKey, retiredKeys)
+	require.NoError(t, err)
+	return keyRing, publicKey
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	keyRing, _ := newTestKeyRing(t, "key-1", nil)
+	maker, err := NewPasetoPublicMaker(keyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	role := util.AuditorRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := time.Now().Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiresAt, time.Second)
+
+	requireSessionToken(t, maker)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdsaKeyRing, err := NewKeyRing("key-1", ecdsaKey, nil)
+	require.NoError(t, err)
+	_, err = NewPasetoPublicMaker(ecdsaKeyRing, testIssuer, testAudience)
+	require.Error(t, err)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	keyRing, _ := newTestKeyRing(t, "key-1", nil)
+	maker, err := NewPasetoPublicMaker(keyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldKeyRing, oldPublicKey := newTestKeyRing(t, "key-1", nil)
+	oldMaker, err := NewPasetoPublicMaker(oldKeyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	oldToken, _, err := oldMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// after the rotation the old key only verifies
+	newKeyRing, _ := newTestKeyRing(t, "key-2", map[string]crypto.PublicKey{"key-1": oldPublicKey})
+	newMaker, err := NewPasetoPublicMaker(newKeyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	payload, err := newMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	newToken, _, err := newMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// once the old key is dropped from the ring, its tokens are rejected
+	otherKeyRing, _ := newTestKeyRing(t, "key-3", nil)
+	otherMaker, err := NewPasetoPublicMaker(otherKeyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	for _, token := range []string{oldToken, newToken} {
+		payload, err = otherMaker.VerifyToken(token)
+		require.EqualError(t, err, ErrInvalidToken.Error())
+		require.Nil(t, payload)
+	}
+}
+
+func TestInvalidPasetoPublicTokenSignature(t *testing.T) {
+	keyRing, _ := newTestKeyRing(t, "key-1", nil)
+	maker, err := NewPasetoPublicMaker(keyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	// a different key claiming the same id must not verify
+	forgedKeyRing, _ := newTestKeyRing(t, "key-1", nil)
+	forgedMaker, err := NewPasetoPublicMaker(forgedKeyRing, testIssuer, testAudience)
+	require.NoError(t, err)
+
+	token, _, err := forgedMaker.CreateToken(util.RandomOwner(), util.AdminRole, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+
+	// symmetric tokens are rejected too
+	symmetricMaker, err := NewPasetoMaker(util.RandomString(32), testIssuer, testAudience)
+	require.NoError(t, err)
+
+	token, _, err = symmetricMaker.CreateToken(util.RandomOwner(), util.AdminRole, time.Minute)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
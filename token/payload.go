@@ -2,6 +2,7 @@ package token
 
 import (
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,17 +10,36 @@ import (
 
 var ErrExpiredToken = errors.New("expired token")
 var ErrInvalidToken = errors.New("token is invalid")
+var ErrRevokedToken = errors.New("token has been revoked")
+var ErrInvalidIssuer = errors.New("token has an invalid issuer")
+var ErrInvalidAudience = errors.New("token has an invalid audience")
 
-// Payload contains the payload data of the token
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeStepUp tokens confirm that the user of a session has given its password or TOTP code again
+	TokenTypeStepUp = "step_up"
+)
+
+// Payload contains the payload data of the token, SessionID is uuid.Nil for tokens without a server-side session
+// and TokenType tells access tokens apart from the refresh tokens that may only be used to renew them.
+// APIKeyID and Scopes are only set for requests authenticated with an API key and are never part of a token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	SessionID uuid.UUID `json:"session_id"`
+	TokenType string    `json:"token_type"`
+	Issuer    string    `json:"iss"`
+	Audience  string    `json:"aud"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	APIKeyID  int64     `json:"-"`
+	Scopes    []string  `json:"-"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, role and duration
+func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
 	tokenId, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -28,16 +48,35 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 	payload := &Payload{
 		ID:        tokenId,
 		Username:  username,
+		Role:      role,
+		TokenType: TokenTypeAccess,
 		IssuedAt:  time.Now(),
 		ExpiresAt: time.Now().Add(duration),
 	}
 	return payload, nil
 }
 
-// Valid checks if the token payload is valid or not
-func (payload *Payload) Valid() error {
+// checkExpiry checks if the token payload has expired or not
+func (payload *Payload) checkExpiry() error {
 	if time.Now().After(payload.ExpiresAt) {
 		return ErrExpiredToken
 	}
 	return nil
 }
+
+// HasScope reports whether the request may use the scope, users are only limited by their role
+// while API keys may only do what their scopes allow
+func (payload *Payload) HasScope(scope string) bool {
+	return payload.APIKeyID == 0 || slices.Contains(payload.Scopes, scope)
+}
+
+// ValidFor checks if the token was issued by the issuer for the audience
+func (payload *Payload) ValidFor(issuer string, audience string) error {
+	if payload.Issuer != issuer {
+		return ErrInvalidIssuer
+	}
+	if payload.Audience != audience {
+		return ErrInvalidAudience
+	}
+	return nil
+}
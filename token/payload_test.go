@@ -0,0 +1,62 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "simple_bank"
+	testAudience = "simple_bank_api"
+)
+
+func TestPayloadValidFor(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload.Issuer = testIssuer
+	payload.Audience = testAudience
+
+	require.NoError(t, payload.ValidFor(testIssuer, testAudience))
+	require.ErrorIs(t, payload.ValidFor("other_issuer", testAudience), ErrInvalidIssuer)
+	require.ErrorIs(t, payload.ValidFor(testIssuer, "other_audience"), ErrInvalidAudience)
+}
+
+func TestPayloadHasScope(t *testing.T) {
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	require.True(t, payload.HasScope(util.ScopeTransfersWrite))
+
+	payload.APIKeyID = 1
+	payload.Scopes = []string{util.ScopeAccountsRead}
+	require.True(t, payload.HasScope(util.ScopeAccountsRead))
+	require.False(t, payload.HasScope(util.ScopeTransfersWrite))
+
+	payload.Scopes = nil
+	require.False(t, payload.HasScope(util.ScopeAccountsRead))
+}
+
+// requireSessionToken checks that the maker keeps the session and type of a token and leaves the session out for plain tokens
+func requireSessionToken(t *testing.T, maker Maker) {
+	sessionID := uuid.New()
+	token, payload, err := maker.CreateSessionToken(util.RandomOwner(), util.DepositorRole, sessionID, TokenTypeRefresh, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, TokenTypeRefresh, payload.TokenType)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, sessionID, payload.SessionID)
+	require.Equal(t, TokenTypeRefresh, payload.TokenType)
+
+	token, _, err = maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, uuid.Nil, payload.SessionID)
+	require.Equal(t, TokenTypeAccess, payload.TokenType)
+}
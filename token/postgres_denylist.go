@@ -0,0 +1,49 @@
+package token
+
+import (
+	"context"
+	"time"
+
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+)
+
+// PostgresDenylist is a Denylist that persists revoked tokens in the database
+type PostgresDenylist struct {
+	querier db.Querier
+}
+
+// NewPostgresDenylist creates a new PostgresDenylist
+func NewPostgresDenylist(querier db.Querier) Denylist {
+	return &PostgresDenylist{querier: querier}
+}
+
+// RevokeToken revokes a single token until it expires
+func (denylist *PostgresDenylist) RevokeToken(ctx context.Context, payload *Payload) error {
+	err := denylist.querier.RevokeToken(ctx, db.RevokeTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return denylist.querier.DeleteExpiredRevokedTokens(ctx)
+}
+
+// RevokeAllTokens revokes every token of a specific username issued before a specific time
+func (denylist *PostgresDenylist) RevokeAllTokens(ctx context.Context, username string, issuedBefore time.Time) error {
+	return denylist.querier.RevokeUserTokens(ctx, db.RevokeUserTokensParams{
+		Username:      username,
+		RevokedBefore: issuedBefore,
+	})
+}
+
+// IsRevoked checks if the token has been revoked or not
+func (denylist *PostgresDenylist) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	return denylist.querier.IsTokenRevoked(ctx, db.IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})
+}
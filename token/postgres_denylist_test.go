@@ -0,0 +1,76 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresDenylist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	denylist := NewPostgresDenylist(store)
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	store.EXPECT().RevokeToken(gomock.Any(), gomock.Eq(db.RevokeTokenParams{
+		ID:        payload.ID,
+		Username:  payload.Username,
+		ExpiresAt: payload.ExpiresAt,
+	})).Times(1).Return(nil)
+	store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Times(1).Return(nil)
+
+	err = denylist.RevokeToken(context.Background(), payload)
+	require.NoError(t, err)
+
+	revokedBefore := time.Now()
+	store.EXPECT().RevokeUserTokens(gomock.Any(), gomock.Eq(db.RevokeUserTokensParams{
+		Username:      payload.Username,
+		RevokedBefore: revokedBefore,
+	})).Times(1).Return(nil)
+
+	err = denylist.RevokeAllTokens(context.Background(), payload.Username, revokedBefore)
+	require.NoError(t, err)
+
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Eq(db.IsTokenRevokedParams{
+		ID:       payload.ID,
+		Username: payload.Username,
+		IssuedAt: payload.IssuedAt,
+	})).Times(1).Return(true, nil)
+
+	revoked, err := denylist.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestPostgresDenylistError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	denylist := NewPostgresDenylist(store)
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	store.EXPECT().RevokeToken(gomock.Any(), gomock.Any()).Times(1).Return(sql.ErrConnDone)
+	store.EXPECT().DeleteExpiredRevokedTokens(gomock.Any()).Times(0)
+
+	err = denylist.RevokeToken(context.Background(), payload)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, sql.ErrConnDone)
+
+	_, err = denylist.IsRevoked(context.Background(), payload)
+	require.ErrorIs(t, err, sql.ErrConnDone)
+}
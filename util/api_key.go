@@ -0,0 +1,45 @@
+package util
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// apiKeyTag starts every API key so leaked keys are easy to spot
+const apiKeyTag = "sbk"
+
+// apiKeyPrefixSize is the number of random bytes of the prefix that identifies a key
+const apiKeyPrefixSize = 6
+
+// apiKeySecretSize is the number of random bytes of the secret part of a key
+const apiKeySecretSize = 32
+
+// GenerateAPIKey creates a new API key of the form sbk_<prefix>_<secret> and returns it with its prefix,
+// the prefix is stored in plain text to identify the key while the key itself is only stored hashed
+func GenerateAPIKey() (key string, prefix string, err error) {
+	b := make([]byte, apiKeyPrefixSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+	prefix = apiKeyTag + "_" + hex.EncodeToString(b)
+
+	secret, err := GenerateSecureToken(apiKeySecretSize)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+	return prefix + "_" + secret, prefix, nil
+}
+
+// ParseAPIKeyPrefix returns the prefix of an API key, ok is false when the key is malformed
+func ParseAPIKeyPrefix(key string) (prefix string, ok bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != apiKeyPrefixSize*2 || parts[2] == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", false
+	}
+	return parts[0] + "_" + parts[1], true
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, prefix+"_"))
+	require.Len(t, prefix, 16)
+
+	parsedPrefix, ok := ParseAPIKeyPrefix(key)
+	require.True(t, ok)
+	require.Equal(t, prefix, parsedPrefix)
+
+	otherKey, otherPrefix, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.NotEqual(t, key, otherKey)
+	require.NotEqual(t, prefix, otherPrefix)
+
+	for _, invalid := range []string{"", "sbk", prefix, prefix + "_", "xyz" + key[3:], "sbk_nothex000000_" + RandomString(43)} {
+		_, ok := ParseAPIKeyPrefix(invalid)
+		require.False(t, ok, invalid)
+	}
+}
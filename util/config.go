@@ -1,17 +1,67 @@
 package util
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                   string        `mapstructure:"DB_DRIVER"`
+	DBSource                   string        `mapstructure:"DB_SOURCE"`
+	ServerAddress              string        `mapstructure:"SERVER_ADDRESS"`
+	TokenType                  string        `mapstructure:"TOKEN_TYPE"`
+	TokenIssuer                string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience              string        `mapstructure:"TOKEN_AUDIENCE"`
+	TokenSymmetricKey          string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenSigningKeyID          string        `mapstructure:"TOKEN_SIGNING_KEY_ID"`
+	TokenSigningKey            string        `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerifyingKeys         string        `mapstructure:"TOKEN_VERIFYING_KEYS"`
+	TokenPrivateKeyFile        string        `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	AccessTokenDuration        time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration       time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	TokenDenylist              string        `mapstructure:"TOKEN_DENYLIST"`
+	PasswordResetTokenDuration time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	PasswordHashAlgorithm      string        `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory               uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations           uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism          uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost                 int           `mapstructure:"BCRYPT_COST"`
+	PasswordMinLength          int           `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength          int           `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordRequireUpper       bool          `mapstructure:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower       bool          `mapstructure:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit       bool          `mapstructure:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol      bool          `mapstructure:"PASSWORD_REQUIRE_SYMBOL"`
+	PasswordDisallowUserInfo   bool          `mapstructure:"PASSWORD_DISALLOW_USER_INFO"`
+	BreachedPasswordsFile      string        `mapstructure:"BREACHED_PASSWORDS_FILE"`
+	LoginMaxFailedAttempts     int64         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginMaxFailedAttemptsIP   int64         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS_IP"`
+	LoginFailureDelay          time.Duration `mapstructure:"LOGIN_FAILURE_DELAY"`
+	LoginLockoutDuration       time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	Notifier                   string        `mapstructure:"NOTIFIER"`
+	NotifierFile               string        `mapstructure:"NOTIFIER_FILE"`
+	SMTPAddress                string        `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername               string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword               string        `mapstructure:"SMTP_PASSWORD"`
+	EmailSenderAddress         string        `mapstructure:"EMAIL_SENDER_ADDRESS"`
+	VerifyEmailURL             string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailDuration        time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	RequireVerifiedEmail       bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	TwoFactorIssuer            string        `mapstructure:"TWO_FACTOR_ISSUER"`
+	TwoFactorEncryptionKey     string        `mapstructure:"TWO_FACTOR_ENCRYPTION_KEY"`
+	TwoFactorChallengeDuration time.Duration `mapstructure:"TWO_FACTOR_CHALLENGE_DURATION"`
+	FxRateProvider             string        `mapstructure:"FX_RATE_PROVIDER"`
+	FxRatesFile                string        `mapstructure:"FX_RATES_FILE"`
+	FxQuoteDuration            time.Duration `mapstructure:"FX_QUOTE_DURATION"`
+	StepUpThresholds           string        `mapstructure:"STEP_UP_THRESHOLDS"`
+	StepUpDuration             time.Duration `mapstructure:"STEP_UP_DURATION"`
+	HoldDuration               time.Duration `mapstructure:"HOLD_DURATION"`
+	HoldExpiryInterval         time.Duration `mapstructure:"HOLD_EXPIRY_INTERVAL"`
+	SchedulerInterval          time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	SchedulerRetryInterval     time.Duration `mapstructure:"SCHEDULER_RETRY_INTERVAL"`
+	SchedulerMaxAttempts       int32         `mapstructure:"SCHEDULER_MAX_ATTEMPTS"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -27,5 +77,16 @@ func LoadConfig(path string) (config Config, err error) {
 	}
 
 	err = viper.Unmarshal(&config)
+	if err != nil {
+		return
+	}
+
+	// the workers tick at these intervals, time.NewTicker panics on a non-positive one
+	switch {
+	case config.HoldExpiryInterval <= 0:
+		err = fmt.Errorf("HOLD_EXPIRY_INTERVAL must be positive, got %s", config.HoldExpiryInterval)
+	case config.SchedulerInterval <= 0:
+		err = fmt.Errorf("SCHEDULER_INTERVAL must be positive, got %s", config.SchedulerInterval)
+	}
 	return
 }
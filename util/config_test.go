@@ -0,0 +1,64 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	testCases := []struct {
+		name  string
+		env   map[string]string
+		check func(t *testing.T, config Config, err error)
+	}{
+		{
+			name: "OK",
+			check: func(t *testing.T, config Config, err error) {
+				require.NoError(t, err)
+				require.Positive(t, config.HoldExpiryInterval)
+				require.Positive(t, config.SchedulerInterval)
+			},
+		},
+		{
+			name: "ZeroHoldExpiryInterval",
+			env:  map[string]string{"HOLD_EXPIRY_INTERVAL": "0s"},
+			check: func(t *testing.T, config Config, err error) {
+				require.EqualError(t, err, "HOLD_EXPIRY_INTERVAL must be positive, got 0s")
+			},
+		},
+		{
+			name: "NegativeHoldExpiryInterval",
+			env:  map[string]string{"HOLD_EXPIRY_INTERVAL": "-1m"},
+			check: func(t *testing.T, config Config, err error) {
+				require.EqualError(t, err, "HOLD_EXPIRY_INTERVAL must be positive, got -1m0s")
+			},
+		},
+		{
+			name: "ZeroSchedulerInterval",
+			env:  map[string]string{"SCHEDULER_INTERVAL": "0s"},
+			check: func(t *testing.T, config Config, err error) {
+				require.EqualError(t, err, "SCHEDULER_INTERVAL must be positive, got 0s")
+			},
+		},
+		{
+			name: "NegativeSchedulerInterval",
+			env:  map[string]string{"SCHEDULER_INTERVAL": "-1m"},
+			check: func(t *testing.T, config Config, err error) {
+				require.EqualError(t, err, "SCHEDULER_INTERVAL must be positive, got -1m0s")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			config, err := LoadConfig("..")
+			tc.check(t, config, err)
+		})
+	}
+}
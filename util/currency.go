@@ -1,5 +1,11 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -14,3 +20,31 @@ func IsSupportedCurrency(currency string) bool {
 		return false
 	}
 }
+
+// ParseCurrencyAmounts parses a comma separated list of CURRENCY:amount pairs, such as USD:10000,EUR:9000
+func ParseCurrencyAmounts(s string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		currency, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid currency amount %q: must be CURRENCY:amount", entry)
+		}
+		if !IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("unsupported currency %q", currency)
+		}
+		if _, ok := amounts[currency]; ok {
+			return nil, fmt.Errorf("duplicate currency %q", currency)
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || amount < 0 {
+			return nil, fmt.Errorf("invalid amount %q for %s: must be a non-negative integer", value, currency)
+		}
+		amounts[currency] = amount
+	}
+	return amounts, nil
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// EncryptSecret seals the plaintext with XChaCha20-Poly1305, the additional data binds the ciphertext
+// to its owner so it cannot be copied to another row
+func EncryptSecret(key string, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// DecryptSecret opens a ciphertext sealed by EncryptSecret
+func DecryptSecret(key string, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, additionalData)
+}
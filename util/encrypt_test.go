@@ -0,0 +1,36 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	key := RandomString(32)
+	secret := []byte(RandomString(16))
+
+	ciphertext1, err := EncryptSecret(key, secret, []byte("alice"))
+	require.NoError(t, err)
+	require.NotContains(t, string(ciphertext1), string(secret))
+
+	ciphertext2, err := EncryptSecret(key, secret, []byte("alice"))
+	require.NoError(t, err)
+	require.NotEqual(t, ciphertext1, ciphertext2)
+
+	plaintext, err := DecryptSecret(key, ciphertext1, []byte("alice"))
+	require.NoError(t, err)
+	require.Equal(t, secret, plaintext)
+
+	_, err = DecryptSecret(key, ciphertext1, []byte("bob"))
+	require.Error(t, err)
+
+	_, err = DecryptSecret(RandomString(32), ciphertext1, []byte("alice"))
+	require.Error(t, err)
+
+	_, err = DecryptSecret(key, ciphertext1[:10], []byte("alice"))
+	require.Error(t, err)
+
+	_, err = EncryptSecret(RandomString(16), secret, nil)
+	require.Error(t, err)
+}
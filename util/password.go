@@ -1,19 +1,194 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// BcryptMaxPasswordBytes is the longest password bcrypt can hash
+const BcryptMaxPasswordBytes = 72
+
+// ErrMismatchedHashAndPassword is returned by every hasher when the password does not match the hash
+var ErrMismatchedHashAndPassword = bcrypt.ErrMismatchedHashAndPassword
+
+// ErrUnsupportedPasswordHash is returned for hashes without a known algorithm prefix,
+// such as the '!' of accounts that must never log in
+var ErrUnsupportedPasswordHash = errors.New("unsupported password hash")
+
+// PasswordHasher is an interface for hashing passwords into strings prefixed with their algorithm
+type PasswordHasher interface {
+	// HashPassword hashes the password with the algorithm and parameters of the hasher
+	HashPassword(password string) (string, error)
+	// CheckPasswordHash checks the password against a hash made with any supported algorithm
+	CheckPasswordHash(password string, hash string) error
+	// NeedsRehash reports whether the hash was made with another algorithm or other parameters
+	NeedsRehash(hash string) bool
+}
+
+// Argon2idParams are the cost parameters of argon2id, Memory is in KiB
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follows the OWASP recommendation for argon2id
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var defaultPasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+
+// HashPassword hashes the password with argon2id and the default parameters
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return defaultPasswordHasher.HashPassword(password)
+}
+
+// CheckPasswordHash checks the password against a hash made with any supported algorithm
+func CheckPasswordHash(password, hash string) error {
+	return defaultPasswordHasher.CheckPasswordHash(password, hash)
+}
+
+// Argon2idHasher is a PasswordHasher for argon2id, encoding hashes in the PHC string format
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher creates a new Argon2idHasher with the parameters
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{
+		params: params,
+	}
+}
+
+// HashPassword hashes the password with a new random salt
+func (hasher *Argon2idHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, hasher.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, hasher.params.Iterations, hasher.params.Memory, hasher.params.Parallelism, hasher.params.KeyLength)
+	return encodeArgon2idHash(hasher.params, salt, key), nil
+}
+
+// CheckPasswordHash checks the password against a hash made with any supported algorithm
+func (hasher *Argon2idHasher) CheckPasswordHash(password string, hash string) error {
+	return checkPasswordHash(password, hash)
+}
+
+// NeedsRehash reports whether the hash was not made with argon2id and the parameters of the hasher
+func (hasher *Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2idHash(hash)
+	return err != nil || params != hasher.params
+}
+
+// BcryptHasher is a PasswordHasher for bcrypt, kept for the hashes made before argon2id,
+// it rejects passwords longer than the 72 bytes bcrypt can hash
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a new BcryptHasher with the cost
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost: must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &BcryptHasher{
+		cost: cost,
+	}, nil
+}
+
+// HashPassword hashes the password with a new random salt
+func (hasher *BcryptHasher) HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %v", err)
 	}
 	return string(hashedPassword), nil
 }
 
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// CheckPasswordHash checks the password against a hash made with any supported algorithm
+func (hasher *BcryptHasher) CheckPasswordHash(password string, hash string) error {
+	return checkPasswordHash(password, hash)
+}
+
+// NeedsRehash reports whether the hash was not made with bcrypt and the cost of the hasher
+func (hasher *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != hasher.cost
+}
+
+// checkPasswordHash picks the algorithm from the prefix of the hash
+func checkPasswordHash(password string, hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := decodeArgon2idHash(hash)
+		if err != nil {
+			return err
+		}
+		otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+			return ErrMismatchedHashAndPassword
+		}
+		return nil
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	default:
+		return ErrUnsupportedPasswordHash
+	}
+}
+
+func encodeArgon2idHash(params Argon2idParams, salt []byte, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2idHash(hash string) (params Argon2idParams, salt []byte, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnsupportedPasswordHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
 }
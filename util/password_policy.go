@@ -0,0 +1,152 @@
+package util
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minUserInfoLength is the shortest username or email name a password is checked for,
+// shorter ones would reject too many unrelated passwords
+const minUserInfoLength = 3
+
+// PasswordPolicyError is a password policy violation, Reason completes a sentence about the password field
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password " + e.Reason
+}
+
+// PasswordPolicy is the set of rules new passwords have to follow, existing passwords are never checked against it
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MaxBytes limits the encoded length for hashers that cannot hash longer passwords, no limit when 0
+	MaxBytes         int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowUserInfo bool
+	// Breached is the list of passwords known from data breaches, no list is checked when nil
+	Breached *BreachedPasswords
+}
+
+// Check returns the first rule the password of the user breaks as a *PasswordPolicyError, nil when it follows them all
+func (policy *PasswordPolicy) Check(password string, username string, email string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("must be at least %d characters", policy.MinLength)}
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("must be at most %d characters", policy.MaxLength)}
+	}
+	if policy.MaxBytes > 0 && len(password) > policy.MaxBytes {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("must be at most %d bytes", policy.MaxBytes)}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	switch {
+	case policy.RequireUpper && !hasUpper:
+		return &PasswordPolicyError{Reason: "must contain an uppercase letter"}
+	case policy.RequireLower && !hasLower:
+		return &PasswordPolicyError{Reason: "must contain a lowercase letter"}
+	case policy.RequireDigit && !hasDigit:
+		return &PasswordPolicyError{Reason: "must contain a digit"}
+	case policy.RequireSymbol && !hasSymbol:
+		return &PasswordPolicyError{Reason: "must contain a symbol"}
+	}
+
+	if policy.DisallowUserInfo {
+		lowerPassword := strings.ToLower(password)
+		if containsUserInfo(lowerPassword, username) {
+			return &PasswordPolicyError{Reason: "must not contain the username"}
+		}
+		emailName, _, _ := strings.Cut(email, "@")
+		if containsUserInfo(lowerPassword, emailName) {
+			return &PasswordPolicyError{Reason: "must not contain the email address"}
+		}
+	}
+
+	if policy.Breached != nil && policy.Breached.Contains(password) {
+		return &PasswordPolicyError{Reason: "has appeared in a data breach, choose another one"}
+	}
+	return nil
+}
+
+func containsUserInfo(lowerPassword string, info string) bool {
+	return utf8.RuneCountInString(info) >= minUserInfoLength && strings.Contains(lowerPassword, strings.ToLower(info))
+}
+
+// BreachedPasswords is a list of SHA-1 hashes of breached passwords, grouped by their first 5 hex characters
+// the same way as k-anonymity range queries so a lookup only ever compares a handful of suffixes
+type BreachedPasswords struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a list of uppercase SHA-1 hashes, one per line and optionally followed by
+// ":<count>" as in the Pwned Passwords downloads, blank lines and lines starting with # are skipped
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached passwords file: %w", err)
+	}
+	defer file.Close()
+
+	breached := &BreachedPasswords{
+		ranges: make(map[string]map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid sha-1 hash on line %d of breached passwords file", lineNumber)
+		}
+
+		prefix, suffix := hash[:5], hash[5:]
+		if breached.ranges[prefix] == nil {
+			breached.ranges[prefix] = make(map[string]struct{})
+		}
+		breached.ranges[prefix][suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read breached passwords file: %w", err)
+	}
+
+	return breached, nil
+}
+
+// Contains reports whether the password is on the list
+func (breached *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := breached.ranges[hash[:5]][hash[5:]]
+	return ok
+}
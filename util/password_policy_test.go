@@ -0,0 +1,89 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	breached, err := LoadBreachedPasswords("breached_passwords.txt")
+	require.NoError(t, err)
+
+	policy := &PasswordPolicy{
+		MinLength:        10,
+		MaxLength:        64,
+		MaxBytes:         BcryptMaxPasswordBytes,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowUserInfo: true,
+		Breached:         breached,
+	}
+
+	testCases := []struct {
+		name     string
+		password string
+		reason   string
+	}{
+		{name: "OK", password: "Correct-Horse-9"},
+		{name: "TooShort", password: "Sh0rt!", reason: "must be at least 10 characters"},
+		{name: "TooLong", password: "Aa1!" + strings.Repeat("a", 61), reason: "must be at most 64 characters"},
+		{name: "TooManyBytes", password: "Aa1!" + strings.Repeat("é", 40), reason: "must be at most 72 bytes"},
+		{name: "NoUpper", password: "correct-horse-9", reason: "must contain an uppercase letter"},
+		{name: "NoLower", password: "CORRECT-HORSE-9", reason: "must contain a lowercase letter"},
+		{name: "NoDigit", password: "Correct-Horse-X", reason: "must contain a digit"},
+		{name: "NoSymbol", password: "CorrectHorse99", reason: "must contain a symbol"},
+		{name: "ContainsUsername", password: "Hello-Alice-42", reason: "must not contain the username"},
+		{name: "ContainsEmailName", password: "Hi-Wonderland-7", reason: "must not contain the email address"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password, "alice", "wonderland@example.com")
+			if tc.reason == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			require.ErrorAs(t, err, &policyErr)
+			require.Equal(t, tc.reason, policyErr.Reason)
+		})
+	}
+
+	// a username shorter than minUserInfoLength is not checked
+	require.NoError(t, policy.Check("Correct-Horse-9", "or", "or@example.com"))
+
+	lenient := &PasswordPolicy{MinLength: 6, Breached: breached}
+	err = lenient.Check("Password1", "alice", "wonderland@example.com")
+	require.EqualError(t, err, "password has appeared in a data breach, choose another one")
+}
+
+func TestLoadBreachedPasswords(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "breached.txt")
+	content := "# sha-1 of \"password\"\n\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n7c4a8d09ca3762af61e59520943dc26494f8941b\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	breached, err := LoadBreachedPasswords(path)
+	require.NoError(t, err)
+	require.True(t, breached.Contains("password"))
+	require.True(t, breached.Contains("123456"))
+	require.False(t, breached.Contains(RandomString(12)))
+
+	invalidPath := filepath.Join(dir, "invalid.txt")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot-a-hash\n"), 0o600))
+
+	_, err = LoadBreachedPasswords(invalidPath)
+	require.EqualError(t, err, "invalid sha-1 hash on line 2 of breached passwords file")
+
+	_, err = LoadBreachedPasswords(filepath.Join(dir, "missing.txt"))
+	require.Error(t, err)
+}
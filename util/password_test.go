@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -26,3 +27,70 @@ func TestPassword(t *testing.T) {
 	require.NotEmpty(t, hashedPassword2)
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestArgon2idHasher(t *testing.T) {
+	params := Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hasher := NewArgon2idHasher(params)
+
+	password := RandomString(100)
+	hashedPassword, err := hasher.HashPassword(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=1024,t=1,p=1$"))
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	require.NoError(t, hasher.CheckPasswordHash(password, hashedPassword))
+
+	// unlike bcrypt, every byte of a long password counts
+	err = hasher.CheckPasswordHash(password[:99]+"x", hashedPassword)
+	require.ErrorIs(t, err, ErrMismatchedHashAndPassword)
+
+	stronger := params
+	stronger.Iterations = 2
+	require.True(t, NewArgon2idHasher(stronger).NeedsRehash(hashedPassword))
+
+	// a legacy bcrypt hash still verifies but has to be rehashed
+	bcryptHasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+	legacyHash, err := bcryptHasher.HashPassword(password[:6])
+	require.NoError(t, err)
+
+	require.NoError(t, hasher.CheckPasswordHash(password[:6], legacyHash))
+	require.True(t, hasher.NeedsRehash(legacyHash))
+}
+
+func TestBcryptHasher(t *testing.T) {
+	_, err := NewBcryptHasher(bcrypt.MinCost - 1)
+	require.Error(t, err)
+
+	hasher, err := NewBcryptHasher(bcrypt.MinCost)
+	require.NoError(t, err)
+
+	password := RandomString(6)
+	hashedPassword, err := hasher.HashPassword(password)
+	require.NoError(t, err)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+	require.NoError(t, hasher.CheckPasswordHash(password, hashedPassword))
+
+	err = hasher.CheckPasswordHash(RandomString(6), hashedPassword)
+	require.ErrorIs(t, err, ErrMismatchedHashAndPassword)
+
+	// bcrypt would silently ignore everything past 72 bytes
+	_, err = hasher.HashPassword(RandomString(73))
+	require.Error(t, err)
+
+	argon2idHash, err := HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, hasher.CheckPasswordHash(password, argon2idHash))
+	require.True(t, hasher.NeedsRehash(argon2idHash))
+}
+
+func TestCheckPasswordHashUnsupported(t *testing.T) {
+	// '!' is the hash of the system user, nothing may ever log in as it
+	for _, hash := range []string{"!", "", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5"} {
+		err := CheckPasswordHash("", hash)
+		require.ErrorIs(t, err, ErrUnsupportedPasswordHash)
+
+		err = CheckPasswordHash("!", hash)
+		require.ErrorIs(t, err, ErrUnsupportedPasswordHash)
+	}
+}
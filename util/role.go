@@ -0,0 +1,12 @@
+package util
+
+const (
+	// DepositorRole is given to every new user, it can only see and use its own accounts
+	DepositorRole = "depositor"
+	// BankerRole posts the deposits and withdrawals of money handed over at the counter for any account
+	BankerRole = "banker"
+	// AdminRole can read every account, freeze or unfreeze accounts and do what bankers do
+	AdminRole = "admin"
+	// AuditorRole can read every account and its ledger but cannot change anything it does not own
+	AuditorRole = "auditor"
+)
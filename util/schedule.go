@@ -0,0 +1,10 @@
+package util
+
+import (
+	"github.com/robfig/cron/v3"
+)
+
+// ParseSchedule parses a standard five-field cron expression, or a descriptor such as @daily or @every 1h
+func ParseSchedule(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
@@ -0,0 +1,21 @@
+package util
+
+const (
+	// ScopeAccountsRead lets an API key read accounts, their entries and holds
+	ScopeAccountsRead = "accounts:read"
+	// ScopeAccountsWrite lets an API key open accounts, move money in and out of them and manage holds
+	ScopeAccountsWrite = "accounts:write"
+	// ScopeTransfersRead lets an API key read transfers and scheduled transfers
+	ScopeTransfersRead = "transfers:read"
+	// ScopeTransfersWrite lets an API key make, reverse and schedule transfers
+	ScopeTransfersWrite = "transfers:write"
+)
+
+func IsSupportedScope(scope string) bool {
+	switch scope {
+	case ScopeAccountsRead, ScopeAccountsWrite, ScopeTransfersRead, ScopeTransfersWrite:
+		return true
+	default:
+		return false
+	}
+}
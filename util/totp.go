@@ -0,0 +1,66 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+// GenerateTOTP creates a new TOTP key for the account, and returns its base32 secret and otpauth URI
+func GenerateTOTP(issuer string, accountName string) (secret string, uri string, err error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate totp key: %v", err)
+	}
+	return key.Secret(), key.URL(), nil
+}
+
+// totpPeriod is how many seconds a TOTP code is valid for
+const totpPeriod = 30
+
+// ValidateTOTP checks the code against the secret, codes of the adjacent 30 second periods are accepted.
+// It returns the time step the code belongs to, so a code that has already been used can be told apart
+func ValidateTOTP(code string, secret string, now time.Time) (int64, bool) {
+	step := now.Unix() / totpPeriod
+	for _, s := range []int64{step, step - 1, step + 1} {
+		expected, err := totp.GenerateCode(secret, time.Unix(s*totpPeriod, 0))
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(code), []byte(expected)) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes creates n random single-use recovery codes formatted as xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage, ignoring case and the dash
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
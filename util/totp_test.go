@@ -0,0 +1,59 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	secret, uri, err := GenerateTOTP("simple_bank", "alice")
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/simple_bank:alice?"))
+	require.Contains(t, uri, "secret="+secret)
+
+	now := time.Unix(1_700_000_010, 0)
+	code, err := totp.GenerateCode(secret, now)
+	require.NoError(t, err)
+	step, ok := ValidateTOTP(code, secret, now)
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/30, step)
+
+	// the code is still accepted in the next period, and reports the step it was made for
+	step, ok = ValidateTOTP(code, secret, now.Add(30*time.Second))
+	require.True(t, ok)
+	require.Equal(t, now.Unix()/30, step)
+
+	oldCode, err := totp.GenerateCode(secret, now.Add(-5*time.Minute))
+	require.NoError(t, err)
+	if oldCode != code {
+		_, ok = ValidateTOTP(oldCode, secret, now)
+		require.False(t, ok)
+	}
+
+	otherSecret, _, err := GenerateTOTP("simple_bank", "alice")
+	require.NoError(t, err)
+	require.NotEqual(t, secret, otherSecret)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	require.Len(t, codes, 10)
+
+	hashes := make(map[string]bool)
+	for _, code := range codes {
+		require.Len(t, code, 11)
+		require.Equal(t, byte('-'), code[5])
+		hashes[HashRecoveryCode(code)] = true
+	}
+	require.Len(t, hashes, 10)
+
+	code := codes[0]
+	require.Equal(t, HashRecoveryCode(code), HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))))
+	require.NotEqual(t, HashRecoveryCode(code), HashRecoveryCode(codes[1]))
+}
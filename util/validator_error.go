@@ -26,3 +26,8 @@ func ValidatorError(err validator.ValidationErrors) map[string]string {
 
 	return out
 }
+
+// PasswordPolicyFieldError reports a password policy violation on the field the same way ValidatorError reports a failed tag
+func PasswordPolicyFieldError(field string, err *PasswordPolicyError) map[string]string {
+	return map[string]string{field: field + " " + err.Reason}
+}
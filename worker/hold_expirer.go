@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+)
+
+// HoldExpirer periodically expires stale holds and releases the amount they reserved
+type HoldExpirer struct {
+	store    db.Store
+	interval time.Duration
+}
+
+// NewHoldExpirer creates a new HoldExpirer running every interval
+func NewHoldExpirer(store db.Store, interval time.Duration) *HoldExpirer {
+	return &HoldExpirer{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Start expires holds right away and then on every tick until the context is cancelled
+func (expirer *HoldExpirer) Start(ctx context.Context) {
+	ticker := time.NewTicker(expirer.interval)
+	defer ticker.Stop()
+
+	for {
+		expirer.expire(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (expirer *HoldExpirer) expire(ctx context.Context) {
+	accounts, err := expirer.store.ExpireHolds(ctx)
+	if err != nil {
+		log.Printf("cannot expire holds: %v", err)
+		return
+	}
+	if accounts > 0 {
+		log.Printf("released expired holds on %d accounts", accounts)
+	}
+}
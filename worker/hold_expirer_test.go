@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoldExpirer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	store.EXPECT().ExpireHolds(gomock.Any()).MinTimes(2).DoAndReturn(func(_ context.Context) (int64, error) {
+		calls++
+		if calls == 1 {
+			return 0, sql.ErrConnDone
+		}
+		cancel()
+		return 1, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		NewHoldExpirer(store, time.Millisecond).Start(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "hold expirer did not stop after the context was cancelled")
+	}
+	require.Equal(t, 2, calls)
+}
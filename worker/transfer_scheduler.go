@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/hanifsyahsn/simple_bank/util"
+)
+
+// scheduledTransferBatchSize caps how many due transfers are run per tick, the rest are picked up on the next one
+const scheduledTransferBatchSize = 100
+
+// TransferScheduler periodically executes the scheduled transfers that are due
+type TransferScheduler struct {
+	store         db.Store
+	interval      time.Duration
+	retryInterval time.Duration
+	maxAttempts   int32
+}
+
+// NewTransferScheduler creates a new TransferScheduler running every interval. Runs failing for
+// insufficient funds are retried after retryInterval, up to maxAttempts times in a row.
+func NewTransferScheduler(store db.Store, interval, retryInterval time.Duration, maxAttempts int32) *TransferScheduler {
+	return &TransferScheduler{
+		store:         store,
+		interval:      interval,
+		retryInterval: retryInterval,
+		maxAttempts:   maxAttempts,
+	}
+}
+
+// Start runs the due transfers right away and then on every tick until the context is cancelled
+func (scheduler *TransferScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		scheduler.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (scheduler *TransferScheduler) runDue(ctx context.Context) {
+	due, err := scheduler.store.ListDueScheduledTransfers(ctx, scheduledTransferBatchSize)
+	if err != nil {
+		log.Printf("cannot list due scheduled transfers: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, scheduled := range due {
+		arg := db.RunScheduledTransferTxParams{
+			ScheduledTransferID: scheduled.ID,
+			DueAt:               scheduled.NextRunAt.Time,
+			RetryAt:             now.Add(scheduler.retryInterval),
+			MaxAttempts:         scheduler.maxAttempts,
+		}
+		if scheduled.Schedule.Valid {
+			schedule, err := util.ParseSchedule(scheduled.Schedule.String)
+			if err != nil {
+				// the schedule would fail to parse on every tick, so the transfer is suspended
+				_, err = scheduler.store.SuspendScheduledTransferTx(ctx, db.SuspendScheduledTransferTxParams{
+					ScheduledTransferID: scheduled.ID,
+					DueAt:               scheduled.NextRunAt.Time,
+					Error:               fmt.Sprintf("cannot parse schedule: %v", err),
+				})
+				switch {
+				case err == nil:
+					log.Printf("scheduled transfer %d suspended, cannot parse its schedule %q", scheduled.ID, scheduled.Schedule.String)
+				case !errors.Is(err, db.ErrScheduledTransferNotDue):
+					log.Printf("cannot suspend scheduled transfer %d: %v", scheduled.ID, err)
+				}
+				continue
+			}
+			// missed occurrences are not caught up, the transfer runs once and moves on to the next one
+			arg.NextRunAt = sql.NullTime{Time: schedule.Next(now), Valid: true}
+		}
+
+		result, err := scheduler.store.RunScheduledTransferTx(ctx, arg)
+		if err != nil {
+			if !errors.Is(err, db.ErrScheduledTransferNotDue) {
+				log.Printf("cannot run scheduled transfer %d: %v", scheduled.ID, err)
+			}
+			continue
+		}
+		if result.Run.Status == db.ScheduledTransferRunFailed {
+			log.Printf("scheduled transfer %d failed, %s: %s", scheduled.ID, result.ScheduledTransfer.Status, result.Run.Error.String)
+		}
+	}
+}
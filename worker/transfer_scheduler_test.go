@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/hanifsyahsn/simple_bank/db/mock"
+	db "github.com/hanifsyahsn/simple_bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferSchedulerRunDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+
+	dueAt := time.Now().Add(-time.Minute)
+	oneOff := db.ScheduledTransfer{
+		ID:        1,
+		NextRunAt: sql.NullTime{Time: dueAt, Valid: true},
+		Status:    db.ScheduledTransferStatusActive,
+	}
+	recurring := db.ScheduledTransfer{
+		ID:        2,
+		Schedule:  sql.NullString{String: "@daily", Valid: true},
+		NextRunAt: sql.NullTime{Time: dueAt, Valid: true},
+		Status:    db.ScheduledTransferStatusActive,
+	}
+	invalid := db.ScheduledTransfer{
+		ID:        3,
+		Schedule:  sql.NullString{String: "every day", Valid: true},
+		NextRunAt: sql.NullTime{Time: dueAt, Valid: true},
+		Status:    db.ScheduledTransferStatusActive,
+	}
+
+	store.EXPECT().ListDueScheduledTransfers(gomock.Any(), gomock.Eq(int32(scheduledTransferBatchSize))).Times(1).
+		Return([]db.ScheduledTransfer{oneOff, recurring, invalid}, nil)
+	store.EXPECT().RunScheduledTransferTx(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(_ context.Context, arg db.RunScheduledTransferTxParams) (db.RunScheduledTransferTxResult, error) {
+			require.Equal(t, dueAt, arg.DueAt)
+			require.Equal(t, int32(3), arg.MaxAttempts)
+			require.WithinDuration(t, time.Now().Add(time.Hour), arg.RetryAt, time.Second)
+
+			switch arg.ScheduledTransferID {
+			case oneOff.ID:
+				require.False(t, arg.NextRunAt.Valid)
+				return db.RunScheduledTransferTxResult{}, db.ErrScheduledTransferNotDue
+			case recurring.ID:
+				require.True(t, arg.NextRunAt.Valid)
+				require.True(t, arg.NextRunAt.Time.After(time.Now()))
+				require.True(t, arg.NextRunAt.Time.Before(time.Now().Add(25*time.Hour)))
+			default:
+				require.FailNow(t, "unexpected scheduled transfer", arg.ScheduledTransferID)
+			}
+			return db.RunScheduledTransferTxResult{}, nil
+		})
+	store.EXPECT().SuspendScheduledTransferTx(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(_ context.Context, arg db.SuspendScheduledTransferTxParams) (db.RunScheduledTransferTxResult, error) {
+			require.Equal(t, invalid.ID, arg.ScheduledTransferID)
+			require.Equal(t, dueAt, arg.DueAt)
+			require.Contains(t, arg.Error, "cannot parse schedule")
+			return db.RunScheduledTransferTxResult{}, nil
+		})
+
+	NewTransferScheduler(store, time.Minute, time.Hour, 3).runDue(context.Background())
+}